@@ -6,12 +6,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"log"
-	"net/http"
-	"net/url"
+	"os"
+	"reflect"
 	"strings"
 
-	"github.com/PuerkitoBio/goquery"
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/enrich"
+	"github.com/lukasmwerner/mark/scrape"
 	"github.com/lukasmwerner/mark/store"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +20,14 @@ import (
 var tags []string
 var title string
 var description string
+var mergeDuplicate bool
+var primary string
+var offline bool
+var noSuggest bool
+var addInteractive bool
+var addFromFile string
+var addClipboard bool
+var addWorkers int
 
 // addCmd represents the add command
 var addCmd = &cobra.Command{
@@ -28,53 +37,171 @@ var addCmd = &cobra.Command{
 
 > NOTICE: This method may call out to the network to gather more info about the page
 
+The bookmark is saved before anything is fetched. If the page can't be reached
+(or with --offline) it is queued and mark enrich fills in the title and
+description later, without overwriting the ones you gave.
+
+If the url is already bookmarked (ignoring differences like http/https,
+trailing slashes, tracking parameters and fragments) the tags are merged into
+the existing bookmark instead of saving a duplicate.
+
+Redirects are followed and the bookmark is saved under the page's
+<link rel="canonical"> if it has one on the same site that isn't just the
+homepage, or else where the redirects ended up.
+Use --primary original to keep the url exactly as given, the other urls are
+still recorded and shown by mark show. With --primary linked Hacker News and
+Reddit discussions are saved under the article they link to.
+
+On a terminal you are then offered tags to add, taken from bookmarks on the
+same domain, bookmarks with similar titles and descriptions, and the page's
+keywords. Use --no-suggest to skip this.
+
+With -i, or when run on a terminal without any flags, the page is fetched
+first and a form prefilled with its title, description and suggested tags is
+shown before saving.
+
+Urls without a scheme are taken to be https, so example.com is saved as
+https://example.com. Besides web pages file:, mailto:, ssh: and obsidian:
+links can be bookmarked, their metadata is not fetched.
+
+Several urls can be added at once with "mark add -" reading stdin,
+--from-file or --clipboard. The text can be a list of urls, or markdown or
+html containing links. Urls that are already bookmarked are skipped, and the
+pages are fetched a few at a time (see --jobs).
+
 Example:
-mark add [--tags list,of,seperated,tags] url`,
+mark add [--tags list,of,seperated,tags] [--merge] [--primary original] url
+mark add -i [url]
+grep -o 'https://[^ ]*' notes.txt | mark add - --tags reading
+mark add --from-file links.md --tags imported`,
 	Args: func(cmd *cobra.Command, args []string) error {
+		if addIsBatch(cmd, args) {
+			return nil
+		}
 		if len(args) < 1 {
+			if addIsInteractive(cmd) {
+				return nil
+			}
 			return errors.New("requires a url")
 		}
-		if _, err := url.Parse(args[0]); err != nil {
-			return errors.Join(fmt.Errorf("unable to parse url: %s", args[0]), err)
-		}
-		return nil
+		return validateURL(args[0])
 	},
 
-	Run: func(cmd *cobra.Command, args []string) {
-		link, _ := url.Parse(args[0])
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 
-		fmt.Println("link", link)
-		fmt.Println("tags", tags)
+		if err := enrich.ValidPrimary(primary); err != nil {
+			return err
+		}
+		batch := addIsBatch(cmd, args)
+		if batch && addInteractive {
+			return errors.New("-i can't be used when adding several urls")
+		}
+		if addInteractive && !isInteractive() {
+			return errors.New("not attached to a terminal, pass the url and flags instead of -i")
+		}
 
 		db, err := store.Open()
 		if err != nil {
-			log.Fatalln("error occured in opening db: ", err.Error())
-			return
+			return errors.Join(errors.New("error occured in opening db"), err)
 		}
 
 		defer db.Close()
 
-		if title == "" {
-			title = fetchTitle(link)
+		runner, err := enrich.NewRunner(db)
+		if err != nil {
+			return err
+		}
+
+		if batch {
+			text, err := readBatch()
+			if err != nil {
+				return err
+			}
+			return addBatch(cmd, db, runner, text)
 		}
 
-		if description == "" {
-			description = fetchDescription(link)
+		if addIsInteractive(cmd) {
+			rawURL := ""
+			if len(args) > 0 {
+				rawURL = args[0]
+			}
+			return addInForm(cmd, db, runner, rawURL)
+		}
+
+		link, err := store.NormalizeURL(args[0])
+		if err != nil {
+			return err
+		}
+		fetchable := scrape.Supports(link)
+
+		if existing, err := store.FindBookmarkByURL(db, link); err == nil {
+			return mergeIntoExisting(db, existing, tags)
 		}
 
 		bm := store.Bookmark{
-			Url:         link.String(),
+			Url:         link,
+			OriginalUrl: link,
 			Tags:        tags,
 			Title:       title,
 			Description: description,
 		}
+		if cmd.Flags().Changed("title") {
+			bm.UserFields = append(bm.UserFields, "title")
+		}
+		if cmd.Flags().Changed("description") {
+			bm.UserFields = append(bm.UserFields, "description")
+		}
 
-		_, err = store.InsertBookmark(db, bm)
+		// rules on the url and title can apply before anything is fetched
+		bm = runner.Rules.Apply(bm)
+
+		// save straight away so nothing is lost when offline, the metadata
+		// is filled in by the enrichment job
+		var id store.BookmarkId
+		err = store.Transaction(db, func(tx *store.Tx) error {
+			var err error
+			id, err = store.InsertBookmark(tx, bm)
+			if err != nil || !fetchable {
+				return err
+			}
+			return store.EnqueueEnrichment(tx, id, primary)
+		})
+		var dup *store.DuplicateError
+		if errors.As(err, &dup) {
+			return mergeIntoExisting(db, dup.Existing, tags)
+		}
 		if err != nil {
-			log.Fatalln("unable to save bookmark: ", err.Error())
-			return
+			return errors.Join(errors.New("unable to save bookmark"), err)
 		}
 
+		if !offline && fetchable {
+			_, warnings, err := runner.Job(cmd.Context(), store.EnrichmentJob{BookmarkId: id, Primary: primary})
+			for _, warning := range warnings {
+				fmt.Fprintln(os.Stderr, "unable to enrich page metadata:", warning)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "unable to fetch page metadata:", err)
+				fmt.Fprintln(os.Stderr, "Saved without metadata, mark enrich will retry")
+			}
+		}
+
+		saved, err := store.GetBookmark(db, id)
+		if err != nil {
+			return err
+		}
+		if !noSuggest && isInteractive() {
+			saved, err = chooseSuggestedTags(db, saved)
+			if err != nil && !errors.Is(err, huh.ErrUserAborted) {
+				return err
+			}
+		}
+
+		printSaved(saved)
+		if offline && fetchable {
+			fmt.Println("\nRun mark enrich to fetch its metadata")
+		}
+		return nil
 	},
 }
 
@@ -90,52 +217,70 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// addCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	addCmd.Flags().BoolVarP(&addInteractive, "interactive", "i", false, "Fill in the bookmark in a form, prefilled from the page")
 	addCmd.Flags().StringSliceVar(&tags, "tags", []string{}, "Tags for bookmark")
 	addCmd.Flags().StringVarP(&title, "title", "t", "", "Overrides the title from the scraper")
 	addCmd.Flags().StringVarP(&description, "description", "d", "", "Sets the link's description")
+	addCmd.Flags().StringVar(&primary, "primary", "canonical", "Which url to save the bookmark under: original,resolved,canonical,linked")
+	addCmd.Flags().BoolVar(&offline, "offline", false, "Save without fetching metadata now, leaving it for mark enrich")
+	addCmd.Flags().StringVar(&addFromFile, "from-file", "", "Add every url in a file, which can be a list, markdown or html")
+	addCmd.Flags().BoolVar(&addClipboard, "clipboard", false, "Add every url on the clipboard")
+	addCmd.Flags().IntVar(&addWorkers, "jobs", enrich.DefaultWorkers, "How many pages to fetch at once when adding several urls")
+	addCmd.Flags().BoolVar(&noSuggest, "no-suggest", false, "Don't offer tags used on similar bookmarks")
+	addCmd.Flags().BoolVar(&mergeDuplicate, "merge", false, "Merge the tags into an existing bookmark for the same url without asking")
+	addCmd.MarkFlagsMutuallyExclusive("from-file", "clipboard")
 }
 
-func fetchTitle(u *url.URL) string {
-	resp, err := http.Get(u.String())
-	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return ""
-	}
+// mergeIntoExisting adds tags to a bookmark that already exists for the url
+// being added, asking first unless --merge was given.
+func mergeIntoExisting(db *store.DB, existing store.Bookmark, tags []string) error {
+	fmt.Printf("Already bookmarked as %d %s %s\n", existing.Id, existing.Title, existing.Url)
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return ""
+	merged := existing
+	merged.Tags = store.MergeTags(existing.Tags, tags)
+	if reflect.DeepEqual(existing.Tags, merged.Tags) {
+		return nil
 	}
 
-	title, exists := doc.Find(`meta[property='og:title']`).Attr("content")
-	if !exists {
-		return strings.TrimSpace(doc.Find("title").Text())
+	if !mergeDuplicate {
+		if !isInteractive() {
+			return errors.New("bookmark already exists, use --merge to add the tags to it")
+		}
+		confirmed := false
+		err := huh.NewConfirm().
+			Title(fmt.Sprintf("Add tags %s to the existing bookmark?", strings.Join(tags, ", "))).
+			Value(&confirmed).
+			Run()
+		if err != nil && !errors.Is(err, huh.ErrUserAborted) {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
 	}
-	return strings.TrimSpace(title)
-
-}
 
-func fetchDescription(u *url.URL) string {
-	resp, err := http.Get(u.String())
+	err := store.UpdateBookmark(db, existing, merged)
 	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return ""
+		return errors.Join(errors.New("unable to update bookmark"), err)
 	}
+	fmt.Printf("Tagged %d with %s\n", existing.Id, strings.Join(merged.Tags, ", "))
+	return nil
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return ""
-	}
+// addIsInteractive reports whether the bookmark should be filled in with the
+// form, either because -i was given or because mark add was run on a
+// terminal without any flags.
+func addIsInteractive(cmd *cobra.Command) bool {
+	return addInteractive || (cmd.Flags().NFlag() == 0 && isInteractive())
+}
 
-	title, exists := doc.Find(`meta[property='og:description']`).Attr("content")
-	if !exists {
-		return ""
-	}
-	return strings.TrimSpace(title)
+// validateURL checks that raw is a url that can be bookmarked.
+func validateURL(raw string) error {
+	_, err := store.NormalizeURL(raw)
+	return err
+}
+
+func printSaved(bookmark store.Bookmark) {
+	fmt.Print("Saved ")
+	printBookmark(bookmark)
 }
@@ -0,0 +1,169 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/atotto/clipboard"
+	"github.com/lukasmwerner/mark/enrich"
+	"github.com/lukasmwerner/mark/scrape"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+// addIsBatch reports whether several urls are being added at once, from
+// stdin, a file or the clipboard.
+func addIsBatch(cmd *cobra.Command, args []string) bool {
+	return (len(args) == 1 && args[0] == "-") || cmd.Flags().Changed("from-file") || addClipboard
+}
+
+// readBatch returns the text holding the urls to add.
+func readBatch() (string, error) {
+	switch {
+	case addFromFile != "":
+		data, err := os.ReadFile(addFromFile)
+		if err != nil {
+			return "", errors.Join(errors.New("unable to read "+addFromFile), err)
+		}
+		return string(data), nil
+	case addClipboard:
+		text, err := clipboard.ReadAll()
+		if err != nil {
+			return "", errors.Join(errors.New("unable to read the clipboard"), err)
+		}
+		return text, nil
+	default:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", errors.Join(errors.New("unable to read stdin"), err)
+		}
+		return string(data), nil
+	}
+}
+
+var linkPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s<>"'()\[\]]+`)
+var htmlPattern = regexp.MustCompile(`(?i)<a\s[^>]*href`)
+
+// extractLinks finds the urls in text, which can be a list of urls, markdown
+// or html. Lines holding a single word, like example.com, are taken to be
+// urls too and returned as an error when they aren't valid. Repeated urls are
+// only returned once.
+func extractLinks(text string) ([]string, []error) {
+	found := []string{}
+	if htmlPattern.MatchString(text) {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(text))
+		if err == nil {
+			doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+				href, _ := s.Attr("href")
+				found = append(found, strings.TrimSpace(href))
+			})
+		}
+	} else {
+		for _, line := range strings.Split(text, "\n") {
+			if fields := strings.Fields(line); len(fields) == 1 && !strings.Contains(fields[0], "://") {
+				found = append(found, fields[0])
+				continue
+			}
+			found = append(found, linkPattern.FindAllString(line, -1)...)
+		}
+	}
+
+	links := []string{}
+	invalid := []error{}
+	seen := map[string]bool{}
+	for _, link := range found {
+		// punctuation ending a sentence isn't part of the url
+		link, err := store.NormalizeURL(strings.TrimRight(link, ".,;:!?"))
+		if err != nil {
+			invalid = append(invalid, err)
+			continue
+		}
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	return links, invalid
+}
+
+// addBatch saves every url found in text, skipping the ones that are
+// already bookmarked, and then fetches their metadata a few at a time.
+func addBatch(cmd *cobra.Command, db *store.DB, runner *enrich.Runner, text string) error {
+	if cmd.Flags().Changed("title") || cmd.Flags().Changed("description") {
+		return errors.New("--title and --description can't be used when adding several urls")
+	}
+
+	links, invalid := extractLinks(text)
+	for _, err := range invalid {
+		fmt.Fprintln(os.Stderr, "skipping", err)
+	}
+	if len(links) == 0 {
+		return errors.New("no urls found")
+	}
+
+	added, skipped := 0, 0
+	jobs := []store.EnrichmentJob{}
+	err := store.Transaction(db, func(tx *store.Tx) error {
+		for _, link := range links {
+			bm := store.Bookmark{Url: link, OriginalUrl: link, Tags: tags}
+			id, err := store.InsertBookmark(tx, runner.Rules.Apply(bm))
+			var dup *store.DuplicateError
+			if errors.As(err, &dup) {
+				skipped++
+				fmt.Printf("Skipped %s, already bookmarked as %d\n", link, dup.Existing.Id)
+				continue
+			}
+			if err != nil {
+				return errors.Join(fmt.Errorf("unable to save %s", link), err)
+			}
+			added++
+			if !scrape.Supports(link) {
+				continue
+			}
+			if err := store.EnqueueEnrichment(tx, id, primary); err != nil {
+				return err
+			}
+			jobs = append(jobs, store.EnrichmentJob{BookmarkId: id, Primary: primary})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	if !offline && len(jobs) > 0 {
+		runner.Workers = addWorkers
+		done := 0
+		err = runner.Run(cmd.Context(), jobs, func(result enrich.Result) {
+			done++
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(os.Stderr, "%d: %s\n", result.Job.BookmarkId, warning)
+			}
+			if result.Err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "[%d/%d] %d: unable to fetch metadata: %s\n", done, len(jobs), result.Job.BookmarkId, result.Err)
+				return
+			}
+			fmt.Printf("[%d/%d] %d\t%s\t%s\n", done, len(jobs), result.Bookmark.Id, result.Bookmark.Title, result.Bookmark.Url)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Added %d, skipped %d already bookmarked and %d invalid, %d failed to fetch\n", added, skipped, len(invalid), failed)
+	if len(jobs) > 0 && (offline || failed > 0) {
+		fmt.Println("Run mark enrich to fetch the missing metadata")
+	}
+	return nil
+}
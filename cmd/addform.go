@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/enrich"
+	"github.com/lukasmwerner/mark/scrape"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+// addInForm fetches the page first and lets the user check the bookmark in a
+// form before saving it. rawURL is asked for when it is empty.
+func addInForm(cmd *cobra.Command, db *store.DB, runner *enrich.Runner, rawURL string) error {
+	if rawURL == "" {
+		err := huh.NewInput().Title("URL").Validate(validateURL).Value(&rawURL).Run()
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	rawURL, err := store.NormalizeURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := store.FindBookmarkByURL(db, rawURL); err == nil {
+		return mergeIntoExisting(db, existing, tags)
+	}
+
+	bm := store.Bookmark{Url: rawURL, OriginalUrl: rawURL, Tags: tags}
+
+	var md *scrape.Metadata
+	if !offline && scrape.Supports(rawURL) {
+		fmt.Fprintf(os.Stderr, "Fetching %s\n", rawURL)
+		md, err = runner.Client.Fetch(cmd.Context(), rawURL)
+		if err != nil {
+			md = nil
+			fmt.Fprintln(os.Stderr, "unable to fetch page metadata:", err)
+		} else {
+			for _, warning := range md.Warnings {
+				fmt.Fprintln(os.Stderr, "unable to enrich page metadata:", warning)
+			}
+			bm = enrich.Apply(bm, md, primary)
+		}
+	}
+	if cmd.Flags().Changed("title") {
+		bm.Title = title
+	}
+	if cmd.Flags().Changed("description") {
+		bm.Description = description
+	}
+	bm = runner.Rules.Apply(bm)
+
+	prefilled := bm
+	tagList := strings.Join(bm.Tags, ", ")
+	suggested := []string{}
+
+	fields := []huh.Field{
+		huh.NewInput().Title("URL").Validate(validateURL).Value(&bm.Url),
+		huh.NewInput().Title("Title").Value(&bm.Title),
+		huh.NewText().Title("Description").Value(&bm.Description),
+		huh.NewInput().Title("Tags").Description("Comma seperated").Value(&tagList),
+	}
+	if !noSuggest {
+		field, err := suggestedTagsField(db, prefilled, &suggested)
+		if err != nil {
+			return err
+		}
+		if field != nil {
+			fields = append(fields, field)
+		}
+	}
+
+	err = huh.NewForm(huh.NewGroup(fields...)).Run()
+	if errors.Is(err, huh.ErrUserAborted) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	bm.Url, err = store.NormalizeURL(bm.Url)
+	if err != nil {
+		return err
+	}
+	bm.Tags = store.MergeTags(strings.Split(tagList, ","), suggested)
+	bm = store.TrackUserEdits(prefilled, bm)
+	if cmd.Flags().Changed("title") {
+		bm.UserFields = store.MergeTags(bm.UserFields, []string{"title"})
+	}
+	if cmd.Flags().Changed("description") {
+		bm.UserFields = store.MergeTags(bm.UserFields, []string{"description"})
+	}
+
+	// the metadata belongs to the old url if it was changed in the form
+	fetched := md != nil && bm.Url == prefilled.Url
+	if !fetched {
+		bm.OriginalUrl = bm.Url
+		bm.ResolvedUrl = ""
+		bm.CanonicalLink = ""
+		bm.Redirects = nil
+	}
+
+	var id store.BookmarkId
+	err = store.Transaction(db, func(tx *store.Tx) error {
+		var err error
+		id, err = store.InsertBookmark(tx, bm)
+		if err != nil || fetched || !scrape.Supports(bm.Url) {
+			return err
+		}
+		return store.EnqueueEnrichment(tx, id, primary)
+	})
+	var dup *store.DuplicateError
+	if errors.As(err, &dup) {
+		return mergeIntoExisting(db, dup.Existing, bm.Tags)
+	}
+	if err != nil {
+		return errors.Join(errors.New("unable to save bookmark"), err)
+	}
+
+	if fetched {
+		for _, warning := range runner.CacheImages(cmd.Context(), id, md) {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+
+	saved, err := store.GetBookmark(db, id)
+	if err != nil {
+		return err
+	}
+	printSaved(saved)
+	if !fetched && scrape.Supports(bm.Url) {
+		fmt.Println("\nRun mark enrich to fetch its metadata")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var backupOutput string
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Backs up the bookmarks",
+	Long: `Takes a snapshot of the store while it may be in use and saves it in the
+backups directory of the store, or in the file given with --output.
+
+A backup is a .tar.gz archive of a copy of data.db, the changes directory,
+config.toml and rules.toml. mark restore puts it back.
+
+mark also takes backups on its own before migrating the database to a new
+version and before bulk, dedupe, retag and restore change bookmarks. The
+newest 10 of those are kept, which can be changed in config.toml, where
+keep = 0 keeps every one of them:
+
+  [backup]
+  automatic = true
+  keep = 10
+
+Example:
+mark backup
+mark backup -o ~/Dropbox/mark.tar.gz`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if backupOutput != "" {
+			err := writeFile(backupOutput, func(w io.Writer) error {
+				return store.WriteBackup(db, w)
+			})
+			if err != nil {
+				return errors.Join(errors.New("unable to back up the store"), err)
+			}
+			fmt.Println("Backed up to " + backupOutput)
+			return nil
+		}
+
+		backup, err := store.CreateBackup(db, "")
+		if err != nil {
+			return errors.Join(errors.New("unable to back up the store"), err)
+		}
+		fmt.Printf("Backed up to %s (%s)\n", backup.Path, formatBytes(backup.Size))
+		return nil
+	},
+}
+
+// backupListCmd represents the backup list command
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the backups, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		backups, err := store.ListBackups(db)
+		if err != nil {
+			return err
+		}
+		for _, backup := range backups {
+			reason := backup.Reason
+			if reason == "" {
+				reason = "manual"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", backup.Created.Format("2006-01-02 15:04:05"), reason, formatBytes(backup.Size), backup.Path)
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups in " + db.BackupDir())
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupListCmd)
+
+	backupCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "Write the backup to a file instead of the backups directory")
+}
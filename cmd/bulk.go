@@ -0,0 +1,221 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var bulkAddTags []string
+var bulkRemoveTags []string
+var bulkSetFields []string
+var bulkDelete bool
+var bulkDryRun bool
+var bulkYes bool
+
+// bulkCmd represents the bulk command
+var bulkCmd = &cobra.Command{
+	Use:   "bulk",
+	Short: "Changes or deletes every bookmark matching a search",
+	Long: `Applies the same change to every bookmark matching the search query.
+
+The affected bookmarks are listed before anything is changed, and all of the
+changes are made in a single transaction so they sync as one batch.
+
+Example:
+mark bulk example.com --add-tag example --remove-tag misc
+mark bulk old stuff --set-field description=
+mark bulk dead links --delete --yes
+
+--set-field takes field=value and can be given several times, fields are
+title, url, description and tags (as a comma seperated list). Since no two
+bookmarks can share a url, url can only be set when the search matches a
+single bookmark. Use --dry-run to only see the preview, and --yes to skip the
+confirmation.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		fields, err := parseSetFields(bulkSetFields)
+		if err != nil {
+			return err
+		}
+		if !bulkDelete && len(bulkAddTags) == 0 && len(bulkRemoveTags) == 0 && len(fields) == 0 {
+			return errors.New("nothing to do, use --add-tag, --remove-tag, --set-field or --delete")
+		}
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		searchQuery := strings.Join(args, " ")
+
+		bookmarks, err := store.SearchBookmarks(db, searchQuery)
+		if err != nil {
+			return errors.Join(errors.New("unable to search bookmarks"), err)
+		}
+		if _, ok := fields["url"]; ok && len(bookmarks) > 1 {
+			return fmt.Errorf("%d bookmarks match, --set-field url can only change one bookmark", len(bookmarks))
+		}
+
+		changed := []store.Bookmark{}
+		updates := map[store.BookmarkId]store.Bookmark{}
+		for _, bookmark := range bookmarks {
+			if bulkDelete {
+				fmt.Printf("%d\t%s\t%s\n\tdelete\n", bookmark.Id, bookmark.Title, bookmark.Url)
+				changed = append(changed, bookmark)
+				continue
+			}
+
+			updated := bookmark
+			for field, value := range fields {
+				setField(&updated, field, value)
+			}
+			updated.Tags = store.MergeTags(updated.Tags, bulkAddTags)
+			updated.Tags = store.RemoveTags(updated.Tags, bulkRemoveTags)
+			if reflect.DeepEqual(bookmark, updated) {
+				continue
+			}
+
+			fmt.Printf("%d\t%s\t%s\n", bookmark.Id, bookmark.Title, bookmark.Url)
+			for _, line := range describeChanges(bookmark, updated) {
+				fmt.Printf("\t%s\n", line)
+			}
+			changed = append(changed, bookmark)
+			updates[bookmark.Id] = store.TrackUserEdits(bookmark, updated)
+		}
+
+		if len(changed) == 0 {
+			fmt.Printf("%d bookmarks matched, nothing to change\n", len(bookmarks))
+			return nil
+		}
+		fmt.Printf("%d of %d matching bookmarks would change\n", len(changed), len(bookmarks))
+
+		if bulkDryRun {
+			return nil
+		}
+		if !bulkYes {
+			if !isInteractive() {
+				return errors.New("not attached to a terminal, use --yes to apply the changes")
+			}
+			confirmed := false
+			err := huh.NewConfirm().Title(fmt.Sprintf("Apply changes to %d bookmarks?", len(changed))).Value(&confirmed).Run()
+			if err != nil && !errors.Is(err, huh.ErrUserAborted) {
+				return err
+			}
+			if !confirmed {
+				return nil
+			}
+		}
+
+		if _, err := store.AutoBackup(db, "bulk"); err != nil {
+			return err
+		}
+
+		err = store.Transaction(db, func(tx *store.Tx) error {
+			for _, bookmark := range changed {
+				var err error
+				if bulkDelete {
+					err = store.DeleteBookmark(tx, bookmark.Id)
+				} else {
+					err = store.UpdateBookmark(tx, bookmark, updates[bookmark.Id])
+				}
+				if err != nil {
+					return errors.Join(fmt.Errorf("unable to change bookmark %d", bookmark.Id), err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Changed %d bookmarks\n", len(changed))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bulkCmd)
+
+	bulkCmd.Flags().StringSliceVar(&bulkAddTags, "add-tag", []string{}, "Adds tags to every matching bookmark")
+	bulkCmd.Flags().StringSliceVar(&bulkRemoveTags, "remove-tag", []string{}, "Removes tags from every matching bookmark")
+	bulkCmd.Flags().StringArrayVar(&bulkSetFields, "set-field", []string{}, "Sets a field on every matching bookmark: field=value")
+	bulkCmd.Flags().BoolVar(&bulkDelete, "delete", false, "Deletes every matching bookmark")
+	bulkCmd.Flags().BoolVarP(&bulkDryRun, "dry-run", "n", false, "Only preview the affected bookmarks")
+	bulkCmd.Flags().BoolVarP(&bulkYes, "yes", "y", false, "Apply the changes without asking")
+	bulkCmd.MarkFlagsMutuallyExclusive("delete", "add-tag")
+	bulkCmd.MarkFlagsMutuallyExclusive("delete", "remove-tag")
+	bulkCmd.MarkFlagsMutuallyExclusive("delete", "set-field")
+}
+
+var bulkFields = []string{"title", "url", "description", "tags"}
+
+func parseSetFields(assignments []string) (map[string]string, error) {
+	fields := map[string]string{}
+	for _, assignment := range assignments {
+		field, value, found := strings.Cut(assignment, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !found {
+			return nil, fmt.Errorf("--set-field expects field=value, got: %s", assignment)
+		}
+		known := false
+		for _, f := range bulkFields {
+			known = known || f == field
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown field %q, expected one of: %s", field, strings.Join(bulkFields, ", "))
+		}
+		if field == "url" {
+			normalized, err := store.NormalizeURL(value)
+			if err != nil {
+				return nil, errors.Join(errors.New("invalid --set-field url"), err)
+			}
+			value = normalized
+		}
+		fields[field] = value
+	}
+	return fields, nil
+}
+
+func setField(bookmark *store.Bookmark, field string, value string) {
+	switch field {
+	case "title":
+		bookmark.Title = value
+	case "url":
+		bookmark.Url = value
+	case "description":
+		bookmark.Description = value
+	case "tags":
+		bookmark.Tags = store.NormalizeTags(strings.Split(value, ","))
+	}
+}
+
+// describeChanges lists the fields that differ between two versions of a
+// bookmark in a human readable form.
+func describeChanges(before store.Bookmark, after store.Bookmark) []string {
+	lines := []string{}
+	if before.Title != after.Title {
+		lines = append(lines, fmt.Sprintf("title: %q -> %q", before.Title, after.Title))
+	}
+	if before.Url != after.Url {
+		lines = append(lines, fmt.Sprintf("url: %s -> %s", before.Url, after.Url))
+	}
+	if before.Description != after.Description {
+		lines = append(lines, fmt.Sprintf("description: %q -> %q", before.Description, after.Description))
+	}
+	if !reflect.DeepEqual(before.Tags, after.Tags) {
+		lines = append(lines, fmt.Sprintf("tags: [%s] -> [%s]", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", ")))
+	}
+	return lines
+}
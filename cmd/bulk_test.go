@@ -0,0 +1,19 @@
+package cmd
+
+import "testing"
+
+func TestParseSetFieldsURL(t *testing.T) {
+	fields, err := parseSetFields([]string{"url=example.com/a", "title=A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["url"] != "https://example.com/a" || fields["title"] != "A" {
+		t.Errorf("got %q", fields)
+	}
+
+	for _, assignment := range []string{"url=", "url=not a url", "size=1", "title"} {
+		if _, err := parseSetFields([]string{assignment}); err == nil {
+			t.Errorf("accepted --set-field %s", assignment)
+		}
+	}
+}
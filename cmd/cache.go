@@ -0,0 +1,76 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var cachePruneDryRun bool
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manages the cached favicons and preview images",
+	Long: `Favicons and preview images are downloaded along with a bookmark's metadata
+and kept in the cache directory of the store location. The cache is not
+synced, every device downloads its own images.
+
+Which images are downloaded can be set in config.toml:
+
+[cache]
+icons = true
+images = false`,
+}
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Removes cached images no bookmark uses anymore",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		stats, err := store.PruneCache(db, cachePruneDryRun)
+		if err != nil {
+			return err
+		}
+
+		verb := "Removed"
+		if cachePruneDryRun {
+			verb = "Would remove"
+		}
+		fmt.Printf("%s %d files (%s) and %d records of deleted bookmarks\n", verb, stats.Files, formatBytes(stats.Bytes), stats.Records)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+
+	cachePruneCmd.Flags().BoolVarP(&cachePruneDryRun, "dry-run", "n", false, "Only show what would be removed")
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
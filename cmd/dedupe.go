@@ -0,0 +1,160 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var dedupeByTitle bool
+var dedupeAuto bool
+var dedupeDryRun bool
+
+// dedupeCmd represents the dedupe command
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Finds and merges duplicate bookmarks",
+	Long: `Groups bookmarks that have the same canonical url (and with --by-title the
+same title) and merges each group into a single bookmark.
+
+Merging unions the tags, keeps the longest description and the earliest
+creation time, and deletes the other bookmarks so the merge syncs to your
+other devices. You will be asked which bookmark to keep for each group,
+or with --auto the oldest bookmark in each group is kept.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if !dedupeAuto && !dedupeDryRun && !isInteractive() {
+			return errors.New("not attached to a terminal, use --auto or --dry-run")
+		}
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		// the canonical rules may have changed since the bookmarks were saved
+		err = store.Transaction(db, store.CanonicalizeBookmarks)
+		if err != nil {
+			return errors.Join(errors.New("unable to canonicalize bookmarks"), err)
+		}
+
+		groups, err := store.FindDuplicates(db, dedupeByTitle)
+		if err != nil {
+			return errors.Join(errors.New("unable to find duplicates"), err)
+		}
+		if len(groups) == 0 {
+			fmt.Println("No duplicates found")
+			return nil
+		}
+
+		type merge struct {
+			keep       store.Bookmark
+			duplicates []store.Bookmark
+		}
+		merges := []merge{}
+		for i, group := range groups {
+			fmt.Printf("Group %d of %d\n", i+1, len(groups))
+			for _, bookmark := range group {
+				fmt.Printf("\t%s\n", describeDuplicate(bookmark))
+			}
+
+			if dedupeDryRun {
+				continue
+			}
+			if dedupeAuto {
+				keep := oldestBookmark(group)
+				duplicates := append(append([]store.Bookmark{}, group[:keep]...), group[keep+1:]...)
+				merges = append(merges, merge{group[keep], duplicates})
+				continue
+			}
+
+			keepIndex := 0
+			options := []huh.Option[int]{}
+			for j, bookmark := range group {
+				options = append(options, huh.NewOption("Keep "+describeDuplicate(bookmark), j))
+			}
+			options = append(options, huh.NewOption("Skip this group", -1))
+			err := huh.NewSelect[int]().Title("Merge into").Options(options...).Value(&keepIndex).Run()
+			if errors.Is(err, huh.ErrUserAborted) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if keepIndex == -1 {
+				continue
+			}
+
+			duplicates := append(append([]store.Bookmark{}, group[:keepIndex]...), group[keepIndex+1:]...)
+			merges = append(merges, merge{group[keepIndex], duplicates})
+		}
+
+		if len(merges) == 0 {
+			return nil
+		}
+
+		if _, err := store.AutoBackup(db, "dedupe"); err != nil {
+			return err
+		}
+
+		err = store.Transaction(db, func(tx *store.Tx) error {
+			for _, m := range merges {
+				if _, err := store.MergeBookmarks(tx, m.keep, m.duplicates); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		removed := 0
+		for _, m := range merges {
+			removed += len(m.duplicates)
+		}
+		fmt.Printf("Merged %d groups, removed %d duplicates\n", len(merges), removed)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+
+	dedupeCmd.Flags().BoolVar(&dedupeByTitle, "by-title", false, "Also treat bookmarks with identical titles as duplicates")
+	dedupeCmd.Flags().BoolVar(&dedupeAuto, "auto", false, "Merge every group into its oldest bookmark without asking")
+	dedupeCmd.Flags().BoolVarP(&dedupeDryRun, "dry-run", "n", false, "Only list the duplicate groups")
+	dedupeCmd.MarkFlagsMutuallyExclusive("auto", "dry-run")
+}
+
+func describeDuplicate(bookmark store.Bookmark) string {
+	created := "unknown"
+	if !bookmark.CreatedAt.IsZero() {
+		created = bookmark.CreatedAt.Format("2006-01-02")
+	}
+	return fmt.Sprintf("%d  %s  %s  %s  [%s]", bookmark.Id, created, bookmark.Title, bookmark.Url, strings.Join(bookmark.Tags, ", "))
+}
+
+// oldestBookmark returns the index of the bookmark that was added first. Ids
+// don't say, imports keep the time a bookmark was added to the browser. A
+// bookmark without a creation time was saved before mark kept track of it,
+// so it counts as older than the rest.
+func oldestBookmark(group []store.Bookmark) int {
+	oldest := 0
+	for i, bookmark := range group {
+		if bookmark.CreatedAt.Before(group[oldest].CreatedAt) {
+			oldest = i
+		}
+	}
+	return oldest
+}
@@ -4,8 +4,9 @@ Copyright © 2024 Lukas Werner <me@lukaswerner.com>
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"reflect"
 	"strings"
 
 	"github.com/charmbracelet/huh"
@@ -13,69 +14,89 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var editSelection selection
+
+var editTitle string
+var editUrl string
+var editDescription string
+var editAddTags []string
+var editRemoveTags []string
+var editSetTags []string
+var editWithEditor bool
+var editFormat string
+
 // editCmd represents the edit command
 var editCmd = &cobra.Command{
 	Use:   "edit",
-	Short: "A brief description of your command",
-	Long: ``,
-	Args: cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {	
+	Short: "Edits the entry of a bookmark",
+	Long: `Edits the bookmark matching the search query.
+
+Fields can be changed directly with flags, which never prompts:
+mark edit <query> [--title t] [--url u] [--description d]
+                  [--add-tag a] [--remove-tag b] [--set-tags list,of,tags]
+
+With --editor the bookmark is opened in $VISUAL or $EDITOR as front-matter
+text (yaml by default, or toml with --format toml) and the changes are applied
+once the editor exits, it can't be combined with the flags above. Otherwise
+an interactive form is shown.
+
+When multiple bookmarks match you will be prompted to pick one, unless
+--first, --all, --index or --id decide for you. Without a terminal an
+ambiguous match exits with status 3 and no match with status 2. An --index
+past the matches exits with status 4.`,
+	Args: editSelection.args,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		fromFlags := editFlagsChanged(cmd)
+		if !fromFlags && !editWithEditor && !isInteractive() {
+			return errors.New("not attached to a terminal, use flags or --editor to edit")
+		}
+
 		db, err := store.Open()
 		if err != nil {
-			log.Panicln(err.Error())
-			return
+			return err
 		}
 		defer db.Close()
 
-		searchQuery := strings.Join(args, " ")
-
-		bookmarks, err := store.SearchBookmarks(db, searchQuery)
-		if err != nil {
-			log.Panicln("unable to search bookmarks", err.Error())
+		bookmarks, err := editSelection.resolve(db, args)
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil
 		}
-		if len(bookmarks) == 0 {
-			fmt.Println("found no bookmarks")
-			return
+		if err != nil {
+			return err
 		}
 
-		if len(bookmarks) != 1 {	
-			pickedIndex := 0
-			options := make([]huh.Option[int], len(bookmarks))
-			for i, bookmark := range bookmarks {
-				options[i] = huh.NewOption(bookmark.Title, i)
+		for _, bookmark := range bookmarks {
+			var updated store.Bookmark
+			switch {
+			case fromFlags:
+				updated = applyEditFlags(cmd, bookmark)
+			case editWithEditor:
+				updated, err = editInEditor(editFormat, bookmark)
+			default:
+				updated, err = editInForm(bookmark)
+			}
+			if errors.Is(err, huh.ErrUserAborted) {
+				return nil
 			}
-			err = huh.NewSelect[int]().Title("Pick your link").Options(options...).Value(&pickedIndex).Run()
 			if err != nil {
-				if err == huh.ErrUserAborted {
-					return
-				}
-				log.Fatalln(err.Error())
+				return err
 			}
-			bookmarks = []store.Bookmark{bookmarks[pickedIndex]}
-		}
-
-		bookmark := bookmarks[0]
-		original_bookmark := bookmark
-
-		tags := strings.Join(bookmark.Tags, ",")
-
-		huh.NewForm(huh.NewGroup(
-			huh.NewInput().Title("Title").Value(&bookmark.Title),
-			huh.NewInput().Title("Tags").Value(&tags),
-			huh.NewInput().Title("URL").Value(&bookmark.Url),
-			huh.NewText().Title("Description").Value(&bookmark.Description),
-		)).Run()
-
-
-		bookmark.Tags = strings.Split(tags, ",")
 
+			if reflect.DeepEqual(bookmark, updated) {
+				fmt.Printf("No changes to %s\n", bookmark.Title)
+				continue
+			}
 
-		err = store.UpdateBookmark(db, original_bookmark, bookmark)
-		if err != nil {
-			log.Fatalln(err.Error())
-			return
+			updated = store.TrackUserEdits(bookmark, updated)
+			err = store.UpdateBookmark(db, bookmark, updated)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Updated %s %s\n", updated.Title, updated.Url)
 		}
-
+		return nil
 	},
 }
 
@@ -91,4 +112,69 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// editCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	editSelection.register(editCmd)
+	editCmd.Flags().StringVarP(&editTitle, "title", "t", "", "Sets the bookmark's title")
+	editCmd.Flags().StringVar(&editUrl, "url", "", "Sets the bookmark's url")
+	editCmd.Flags().StringVarP(&editDescription, "description", "d", "", "Sets the bookmark's description")
+	editCmd.Flags().StringSliceVar(&editAddTags, "add-tag", []string{}, "Adds tags to the bookmark")
+	editCmd.Flags().StringSliceVar(&editRemoveTags, "remove-tag", []string{}, "Removes tags from the bookmark")
+	editCmd.Flags().StringSliceVar(&editSetTags, "set-tags", []string{}, "Replaces all of the bookmark's tags")
+	editCmd.Flags().BoolVarP(&editWithEditor, "editor", "e", false, "Edit the bookmark as text in $EDITOR")
+	editCmd.Flags().StringVar(&editFormat, "format", "yaml", "Front matter format used with --editor: yaml,toml")
+	editCmd.MarkFlagsMutuallyExclusive("set-tags", "add-tag")
+	editCmd.MarkFlagsMutuallyExclusive("set-tags", "remove-tag")
+	for _, name := range editFieldFlags {
+		editCmd.MarkFlagsMutuallyExclusive("editor", name)
+	}
+}
+
+var editFieldFlags = []string{"title", "url", "description", "add-tag", "remove-tag", "set-tags"}
+
+func editFlagsChanged(cmd *cobra.Command) bool {
+	for _, name := range editFieldFlags {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func applyEditFlags(cmd *cobra.Command, bookmark store.Bookmark) store.Bookmark {
+	flags := cmd.Flags()
+	if flags.Changed("title") {
+		bookmark.Title = editTitle
+	}
+	if flags.Changed("url") {
+		bookmark.Url = editUrl
+	}
+	if flags.Changed("description") {
+		bookmark.Description = editDescription
+	}
+	if flags.Changed("set-tags") {
+		bookmark.Tags = store.NormalizeTags(editSetTags)
+	}
+	if flags.Changed("add-tag") {
+		bookmark.Tags = store.MergeTags(bookmark.Tags, editAddTags)
+	}
+	if flags.Changed("remove-tag") {
+		bookmark.Tags = store.RemoveTags(bookmark.Tags, editRemoveTags)
+	}
+	return bookmark
+}
+
+func editInForm(bookmark store.Bookmark) (store.Bookmark, error) {
+	tags := strings.Join(bookmark.Tags, ",")
+
+	err := huh.NewForm(huh.NewGroup(
+		huh.NewInput().Title("Title").Value(&bookmark.Title),
+		huh.NewInput().Title("Tags").Value(&tags),
+		huh.NewInput().Title("URL").Value(&bookmark.Url),
+		huh.NewText().Title("Description").Value(&bookmark.Description),
+	)).Run()
+	if err != nil {
+		return bookmark, err
+	}
+
+	bookmark.Tags = store.NormalizeTags(strings.Split(tags, ","))
+	return bookmark, nil
 }
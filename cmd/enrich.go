@@ -0,0 +1,91 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lukasmwerner/mark/enrich"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var enrichRetryFailed bool
+var enrichList bool
+
+// enrichCmd represents the enrich command
+var enrichCmd = &cobra.Command{
+	Use:   "enrich",
+	Short: "Fetches metadata for bookmarks added while offline",
+	Long: `Fetches the title, description and urls of bookmarks whose metadata is
+still pending, because they were added with --offline or the page couldn't be
+reached at the time.
+
+Failed fetches are retried with backoff, starting after a minute and doubling
+up to a day. After ` + fmt.Sprint(store.MaxEnrichmentAttempts) + ` attempts a bookmark is only retried with --failed.
+Titles and descriptions you set by hand are never overwritten.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if enrichList {
+			jobs, err := store.PendingEnrichments(db, time.Now(), true)
+			if err != nil {
+				return err
+			}
+			for _, job := range jobs {
+				status := "due " + job.NextAttempt.Format("2006-01-02 15:04")
+				if job.Failed() {
+					status = "failed"
+				}
+				fmt.Printf("%d\t%d attempts\t%s\t%s\n", job.BookmarkId, job.Attempts, status, job.LastError)
+			}
+			return nil
+		}
+
+		runner, err := enrich.NewRunner(db)
+		if err != nil {
+			return err
+		}
+
+		enriched, failed := 0, 0
+		err = runner.Pending(cmd.Context(), enrichRetryFailed, func(result enrich.Result) {
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(os.Stderr, "%d: %s\n", result.Job.BookmarkId, warning)
+			}
+			if result.Err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "%d: unable to fetch metadata: %s\n", result.Job.BookmarkId, result.Err)
+				return
+			}
+			enriched++
+			fmt.Printf("%d\t%s\t%s\n", result.Bookmark.Id, result.Bookmark.Title, result.Bookmark.Url)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Enriched %d bookmarks, %d failed\n", enriched, failed)
+		if failed > 0 {
+			return errors.New("some bookmarks could not be enriched, they will be retried later")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(enrichCmd)
+
+	enrichCmd.Flags().BoolVar(&enrichRetryFailed, "failed", false, "Also retry bookmarks that failed too often, ignoring the backoff")
+	enrichCmd.Flags().BoolVarP(&enrichList, "list", "l", false, "List the pending bookmarks instead of fetching them")
+}
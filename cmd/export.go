@@ -0,0 +1,453 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/output"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var exportOutput string
+var exportFolders bool
+var exportLines bool
+var exportGroup string
+var exportTitle string
+var exportFeedTags []string
+var exportFeedFormat string
+var exportFeedLimit int
+var exportFeedLink string
+var exportTemplate string
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports bookmarks for browsers and other tools",
+	Long: `Exports every bookmark, or only the ones matching a search query, to stdout
+or to the file given with --output.`,
+}
+
+// exportHTMLCmd represents the export html command
+var exportHTMLCmd = &cobra.Command{
+	Use:   "html [query]",
+	Short: "Exports a bookmarks.html file browsers can import",
+	Long: `Exports the Netscape bookmarks.html format that browsers import.
+
+Tags are kept in the TAGS attribute along with descriptions and the dates
+bookmarks were added and last changed, which mark import html reads back.
+Notes, unread and the metadata found when enriching bookmarks, like their
+original url and authors, have no place in the format and are left out, use
+mark export json to keep everything. With --folders every bookmark is also
+put in a folder for each of its tags, for browsers that ignore TAGS.
+
+Example:
+mark export html -o bookmarks.html
+mark export html golang --folders`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		bookmarks, err := exportBookmarks(db, args)
+		if err != nil {
+			return err
+		}
+
+		return writeExport(func(w io.Writer) error {
+			return exporter.WriteNetscape(w, bookmarks, exporter.NetscapeOptions{FolderPerTag: exportFolders})
+		})
+	},
+}
+
+// exportJSONCmd represents the export json command
+var exportJSONCmd = &cobra.Command{
+	Use:   "json [query]",
+	Short: "Exports every field of the bookmarks as json",
+	Long: `Exports bookmarks in mark's own json format, which keeps every field so
+mark import json can restore them, ids and timestamps included.
+
+The export starts with a header naming the format and its version:
+
+  {"format":"mark","version":1,"exported_at":"2024-05-01T10:00:00Z","bookmarks":[
+  {"id":1,"url":"https://example.com","title":"Example","description":"",
+   "notes":"read later","tags":["a"],"unread":true,"created_at":"2024-04-01T08:00:00Z",
+   "updated_at":"2024-04-01T08:00:00Z","original_url":"https://example.com"}
+  ]}
+
+The other fields are resolved_url, canonical_link, redirects, user_fields,
+keywords, linked_url, authors, stars and duration (in seconds), and fields
+that are empty may be left out. With --lines the
+header is the first line and every following line is a bookmark (json
+lines), which tools can stream through without reading the whole export.
+Either way bookmarks are written as they are read from the store, and mark
+import json reads them back the same way, so large stores are never held in
+memory whole.
+The version only changes when fields are removed or change meaning.
+
+Example:
+mark export json -o backup.json
+mark export json --lines | jq -r .url`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		// bookmarks are written as they are read, large stores never have
+		// to fit in memory
+		return writeExport(func(w io.Writer) error {
+			jw, err := exporter.NewJSONWriter(w, exporter.JSONOptions{Lines: exportLines})
+			if err != nil {
+				return err
+			}
+			err = store.EachBookmark(db, strings.Join(args, " "), jw.Write)
+			if err != nil {
+				return errors.Join(errors.New("unable to list bookmarks"), err)
+			}
+			return jw.Close()
+		})
+	},
+}
+
+// exportMarkdownCmd represents the export markdown command
+var exportMarkdownCmd = &cobra.Command{
+	Use:   "markdown [query]",
+	Short: "Exports the bookmarks as a markdown list",
+	Long: `Exports the bookmarks as a markdown list with a heading for every tag, or
+for every domain with --group domain.
+
+The list is rendered from the template markdown.tmpl, see mark export
+templates to change it.
+
+Example:
+mark export markdown golang --title "Go reading" -o go.md`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exportTemplated(args, exporter.WriteMarkdown)
+	},
+}
+
+// exportOrgCmd represents the export org command
+var exportOrgCmd = &cobra.Command{
+	Use:   "org [query]",
+	Short: "Exports the bookmarks as an org-mode outline",
+	Long: `Exports the bookmarks as an org-mode outline with a heading for every tag,
+or for every domain with --group domain.
+
+The outline is rendered from the template org.tmpl, see mark export
+templates to change it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exportTemplated(args, exporter.WriteOrg)
+	},
+}
+
+// exportSiteCmd represents the export site command
+var exportSiteCmd = &cobra.Command{
+	Use:   "site [query] -o <dir>",
+	Short: "Exports the bookmarks as a static html site",
+	Long: `Exports the bookmarks as a static html site in the directory given with
+--output: an index.html listing every bookmark with a search box, a page for
+every tag in tags/, and the style.css and search.js they use.
+
+The search runs in the browser over the bookmarks embedded in index.html, so
+the directory can be put on any web host or opened straight from disk.
+Running the export again into the same directory updates it, removing the
+pages of tags that are gone. Which files mark wrote is kept in
+.mark-site.json, other files in the directory are left alone.
+
+The pages are rendered from the templates in site/, see mark export
+templates to change them.
+
+Example:
+mark export site -o ~/public/bookmarks --title "Links"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if exportOutput == "" || exportOutput == "-" {
+			return errors.New("give the directory to write the site to with --output")
+		}
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		bookmarks, err := exportBookmarks(db, args)
+		if err != nil {
+			return err
+		}
+
+		err = exporter.WriteSite(exportOutput, bookmarks, exporter.TemplateOptions{
+			Title:     exportTitle,
+			GroupBy:   exportGroup,
+			Templates: exporter.Templates(templatesDir(db)),
+		})
+		if err != nil {
+			return errors.Join(errors.New("unable to write the site to "+exportOutput), err)
+		}
+		fmt.Printf("Wrote %d bookmarks to %s\n", len(bookmarks), filepath.Join(exportOutput, "index.html"))
+		return nil
+	},
+}
+
+// exportFeedCmd represents the export feed command
+var exportFeedCmd = &cobra.Command{
+	Use:   "feed [query]",
+	Short: "Exports the newest bookmarks as an Atom or RSS feed",
+	Long: `Exports the newest bookmarks as an Atom (the default) or RSS 2.0 feed, with
+their titles, links, descriptions as summaries and tags as categories, and
+the time they were added as when they were published.
+
+Use --tag to only include bookmarks with the tag, given more than once a
+bookmark needs every one of them. mark serve serves the same feeds live.
+
+Example:
+mark export feed --tag golang -o golang.atom
+mark export feed --format rss --limit 20`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		bookmarks, err := exportBookmarks(db, args)
+		if err != nil {
+			return err
+		}
+		bookmarks = filterTags(bookmarks, exportFeedTags)
+
+		title := exportTitle
+		if !cmd.Flags().Changed("title") {
+			title = feedTitle(exportFeedTags)
+		}
+		return writeExport(func(w io.Writer) error {
+			return exporter.WriteFeed(w, exportFeedFormat, bookmarks, exporter.FeedOptions{
+				Title: title,
+				Link:  exportFeedLink,
+				Limit: exportFeedLimit,
+			})
+		})
+	},
+}
+
+// exportTemplatesCmd represents the export templates command
+var exportTemplatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Copies the export templates into the store to change them",
+	Long: `Copies the templates of the markdown, org and site exports into the
+templates directory of the mark store, where they replace the built in ones
+once changed. Templates that are already there are left alone, so deleting
+one and running this again restores the default.
+
+Templates use Go's text/template (html/template for the site) and have the
+functions domain, join, date, oneline, md, mdurl, org and orgurl, and tagpage
+in the site.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		written, err := exporter.WriteDefaultTemplates(templatesDir(db))
+		if err != nil {
+			return errors.Join(errors.New("unable to write the templates"), err)
+		}
+		for _, file := range written {
+			fmt.Println(file)
+		}
+		if len(written) == 0 {
+			fmt.Println("The templates are already in " + templatesDir(db))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportHTMLCmd)
+	exportCmd.AddCommand(exportJSONCmd)
+	exportCmd.AddCommand(exportMarkdownCmd)
+	exportCmd.AddCommand(exportOrgCmd)
+	exportCmd.AddCommand(exportSiteCmd)
+	exportCmd.AddCommand(exportFeedCmd)
+	exportCmd.AddCommand(exportTemplatesCmd)
+	for _, formatter := range output.Registered() {
+		if !hasSubcommand(exportCmd, formatter.Name()) {
+			exportCmd.AddCommand(exportFormatterCmd(formatter))
+		}
+	}
+
+	exportCmd.PersistentFlags().StringVarP(&exportOutput, "output", "o", "", "Write to a file instead of stdout")
+	exportHTMLCmd.Flags().BoolVar(&exportFolders, "folders", false, "Put bookmarks in a folder for each of their tags")
+	exportJSONCmd.Flags().BoolVar(&exportLines, "lines", false, "Write json lines, one bookmark per line")
+	exportFeedCmd.Flags().StringSliceVar(&exportFeedTags, "tag", []string{}, "Only include bookmarks with the tag")
+	exportFeedCmd.Flags().StringVar(&exportFeedFormat, "format", exporter.FeedAtom, "Feed format: atom,rss")
+	exportFeedCmd.Flags().IntVar(&exportFeedLimit, "limit", defaultFeedLimit, "How many of the newest bookmarks to include, 0 for all")
+	exportFeedCmd.Flags().StringVar(&exportFeedLink, "link", "", "The page the feed links to, like where the bookmarks are published")
+	exportFeedCmd.Flags().StringVar(&exportTitle, "title", "Bookmarks", "The title of the feed")
+	for _, templated := range []*cobra.Command{exportMarkdownCmd, exportOrgCmd, exportSiteCmd} {
+		templated.Flags().StringVar(&exportGroup, "group", exporter.GroupByTag, "Group bookmarks by tag, domain or none")
+		templated.Flags().StringVar(&exportTitle, "title", "Bookmarks", "The title of the export")
+	}
+}
+
+// exportFormatterCmd makes the export command of an output format that has no
+// command of its own.
+func exportFormatterCmd(formatter output.Formatter) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   formatter.Name() + " [query]",
+		Short: "Exports the bookmarks as " + formatter.Description(),
+		Long: "Exports the bookmarks as " + formatter.Description() + `, like
+mark show and mark list print them with --mode ` + formatter.Name() + ".",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			db, err := store.Open()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			bookmarks, err := exportBookmarks(db, args)
+			if err != nil {
+				return err
+			}
+
+			return writeExport(func(w io.Writer) error {
+				return formatter.Format(w, bookmarks, output.Options{Template: exportTemplate})
+			})
+		},
+	}
+	if formatter.Name() == "template" {
+		cmd.Flags().StringVar(&exportTemplate, "template", output.DefaultTemplate, "The text/template written for every bookmark")
+	}
+	return cmd
+}
+
+// exportBookmarks returns the bookmarks matching the query, or all of them
+// when there is none.
+func exportBookmarks(db *store.DB, args []string) ([]store.Bookmark, error) {
+	if len(args) == 0 {
+		bookmarks, err := store.ListBookmarks(db)
+		if err != nil {
+			return nil, errors.Join(errors.New("unable to list bookmarks"), err)
+		}
+		return bookmarks, nil
+	}
+	bookmarks, err := store.SearchBookmarks(db, strings.Join(args, " "))
+	if err != nil {
+		return nil, errors.Join(errors.New("unable to search bookmarks"), err)
+	}
+	return bookmarks, nil
+}
+
+// exportTemplated writes the bookmarks matching the query with a templated
+// format.
+func exportTemplated(args []string, write func(io.Writer, []store.Bookmark, exporter.TemplateOptions) error) error {
+	db, err := store.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	bookmarks, err := exportBookmarks(db, args)
+	if err != nil {
+		return err
+	}
+
+	opts := exporter.TemplateOptions{
+		Title:     exportTitle,
+		GroupBy:   exportGroup,
+		Templates: exporter.Templates(templatesDir(db)),
+	}
+	return writeExport(func(w io.Writer) error {
+		return write(w, bookmarks, opts)
+	})
+}
+
+// defaultFeedLimit keeps feeds of large stores small, feed readers only
+// look at the newest entries anyway.
+const defaultFeedLimit = 50
+
+// filterTags keeps the bookmarks that have every one of tags.
+func filterTags(bookmarks []store.Bookmark, tags []string) []store.Bookmark {
+	if len(tags) == 0 {
+		return bookmarks
+	}
+	kept := []store.Bookmark{}
+	for _, b := range bookmarks {
+		if b.HasTags(tags...) {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+// feedTitle names a feed after the tags it is limited to.
+func feedTitle(tags []string) string {
+	if len(tags) == 0 {
+		return "Bookmarks"
+	}
+	return "Bookmarks tagged " + strings.Join(tags, ", ")
+}
+
+// templatesDir holds the user's export templates.
+func templatesDir(db *store.DB) string {
+	return filepath.Join(db.StoreLoc, "templates")
+}
+
+// writeExport runs write on stdout or on the --output file. The file is only
+// replaced once the export has been written completely.
+func writeExport(write func(w io.Writer) error) error {
+	if exportOutput == "" || exportOutput == "-" {
+		return write(os.Stdout)
+	}
+	return writeFile(exportOutput, write)
+}
+
+// writeFile runs write on a temporary file that replaces name once it has
+// been written completely.
+func writeFile(name string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".mark-export-*")
+	if err != nil {
+		return errors.Join(errors.New("unable to write "+name), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,277 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+
+	"github.com/lukasmwerner/mark/feeds"
+	"github.com/lukasmwerner/mark/scrape"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var feedsTitle string
+var feedsTags []string
+var feedsFilter string
+var feedsBackfill bool
+var feedsEnrich bool
+var feedsDryRun bool
+
+// feedsCmd represents the feeds command
+var feedsCmd = &cobra.Command{
+	Use:   "feeds",
+	Short: "Bookmarks the new items of RSS and Atom feeds",
+	Long: `Subscribes to RSS and Atom feeds and bookmarks their new items when they
+are polled, tagged with the name of the feed.
+
+Subscriptions are kept on this host only, so a feed is polled by one host
+and the bookmarks it adds sync to the rest. Use mark import opml to
+subscribe to the feeds exported from a feed reader.
+
+Example:
+mark feeds add https://go.dev/blog/feed.atom --title "Go blog"
+mark feeds add https://news.ycombinator.com/rss --filter "golang|sqlite"
+mark feeds poll`,
+}
+
+// feedsAddCmd represents the feeds add command
+var feedsAddCmd = &cobra.Command{
+	Use:   "add <url>",
+	Short: "Subscribes to a feed",
+	Long: `Subscribes to a feed. Its items are tagged with --title, or the feed's own
+title, and --tags. With --filter only items whose title, url or summary
+match the regular expression are bookmarked.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		link, err := store.NormalizeURL(args[0])
+		if err != nil {
+			return err
+		}
+		if !scrape.Supports(link) {
+			return errors.New("feeds have to be http or https urls")
+		}
+		if _, err := regexp.Compile(feedsFilter); err != nil {
+			return errors.Join(errors.New("invalid filter"), err)
+		}
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		err = store.AddFeed(db, store.Feed{Url: link, Title: feedsTitle, Tags: feedsTags, Filter: feedsFilter})
+		if err != nil {
+			return errors.Join(errors.New("unable to subscribe to "+link), err)
+		}
+		fmt.Println("Subscribed to", link)
+		return nil
+	},
+}
+
+// feedsListCmd represents the feeds list command
+var feedsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the subscribed feeds",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		subscriptions, err := store.ListFeeds(db)
+		if err != nil {
+			return err
+		}
+		for i, feed := range subscriptions {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(feed.Title)
+			fmt.Printf("url:    %s\n", feed.Url)
+			if len(feed.Tags) > 0 {
+				fmt.Printf("tags:   %s\n", strings.Join(feed.Tags, ", "))
+			}
+			if feed.Filter != "" {
+				fmt.Printf("filter: %s\n", feed.Filter)
+			}
+			if !feed.PolledAt.IsZero() {
+				fmt.Printf("polled: %s\n", feed.PolledAt.Format("2006-01-02 15:04"))
+			}
+			if feed.LastError != "" {
+				fmt.Printf("error:  %s\n", feed.LastError)
+			}
+		}
+		return nil
+	},
+}
+
+// feedsRemoveCmd represents the feeds remove command
+var feedsRemoveCmd = &cobra.Command{
+	Use:   "remove <url>",
+	Short: "Unsubscribes from a feed, keeping its bookmarks",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		link := args[0]
+		if normalized, err := store.NormalizeURL(link); err == nil {
+			if _, err := store.GetFeed(db, normalized); err == nil {
+				link = normalized
+			}
+		}
+		err = store.RemoveFeed(db, link)
+		if errors.Is(err, store.ErrNotFound) {
+			return errors.New("not subscribed to " + args[0])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("Unsubscribed from", link)
+		return nil
+	},
+}
+
+// feedsPollCmd represents the feeds poll command
+var feedsPollCmd = &cobra.Command{
+	Use:   "poll [url...]",
+	Short: "Bookmarks the new items of the subscribed feeds",
+	Long: `Fetches every subscribed feed, or the ones given, and bookmarks the items
+it hasn't seen before, marked unread and tagged with the feed's name and
+tags. The rules in rules.toml are applied as well.
+
+The items already in a feed when it is polled for the first time are only
+remembered, use --backfill to bookmark them too. Feeds that haven't changed
+since the last poll aren't downloaded again, so poll as often as you like,
+for example from cron.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		subscriptions, err := store.ListFeeds(db)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			subscriptions, err = selectFeeds(subscriptions, args)
+			if err != nil {
+				return err
+			}
+		}
+		if len(subscriptions) == 0 {
+			fmt.Println("No feeds to poll, subscribe with mark feeds add")
+			return nil
+		}
+
+		poller, err := feeds.NewPoller(db)
+		if err != nil {
+			return err
+		}
+		poller.Backfill = feedsBackfill
+		poller.Enrich = feedsEnrich
+		poller.DryRun = feedsDryRun
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		added, failed := 0, 0
+		for _, feed := range subscriptions {
+			if ctx.Err() != nil {
+				break
+			}
+			result := poller.Poll(ctx, feed)
+			name := result.Feed.Title
+			if name == "" {
+				name = result.Feed.Url
+			}
+			switch {
+			case result.Err != nil:
+				failed++
+				fmt.Fprintf(os.Stderr, "%s: %s\n", name, result.Err)
+			case result.NotModified:
+				fmt.Printf("%s: unchanged\n", name)
+			default:
+				added += len(result.Added)
+				fmt.Printf("%s: %d new of %d items, bookmarked %d\n", name, result.New, result.Items, len(result.Added))
+				if result.Invalid > 0 {
+					fmt.Printf("  %d items have no usable link and are tried again next time\n", result.Invalid)
+				}
+			}
+			for _, bm := range result.Added {
+				fmt.Printf("  %d %s\n", bm.Id, bm.Url)
+			}
+		}
+
+		verb := "Bookmarked"
+		if feedsDryRun {
+			verb = "Would bookmark"
+		}
+		fmt.Printf("%s %d items from %d feeds\n", verb, added, len(subscriptions)-failed)
+		if feedsEnrich && !feedsDryRun && added > 0 {
+			fmt.Println("Run mark enrich to fetch their metadata")
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d feeds failed", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(feedsCmd)
+	feedsCmd.AddCommand(feedsAddCmd)
+	feedsCmd.AddCommand(feedsListCmd)
+	feedsCmd.AddCommand(feedsRemoveCmd)
+	feedsCmd.AddCommand(feedsPollCmd)
+
+	feedsAddCmd.Flags().StringVar(&feedsTitle, "title", "", "The name the feed's items are tagged with, instead of the feed's title")
+	feedsAddCmd.Flags().StringSliceVar(&feedsTags, "tags", []string{}, "Tags added to the feed's items")
+	feedsAddCmd.Flags().StringVar(&feedsFilter, "filter", "", "Only bookmark items matching the regular expression")
+	feedsPollCmd.Flags().BoolVar(&feedsBackfill, "backfill", false, "Bookmark the items of feeds polled for the first time")
+	feedsPollCmd.Flags().BoolVar(&feedsEnrich, "enrich", false, "Queue the new bookmarks for mark enrich to fetch their metadata")
+	feedsPollCmd.Flags().BoolVarP(&feedsDryRun, "dry-run", "n", false, "Only count what would be bookmarked")
+}
+
+// selectFeeds picks the subscriptions with the given urls.
+func selectFeeds(subscriptions []store.Feed, urls []string) ([]store.Feed, error) {
+	selected := []store.Feed{}
+	for _, u := range urls {
+		found := false
+		for _, feed := range subscriptions {
+			if normalized, _ := store.NormalizeURL(u); feed.Url == u || feed.Url == normalized {
+				selected = append(selected, feed)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("not subscribed to " + u)
+		}
+	}
+	return selected, nil
+}
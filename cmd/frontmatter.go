@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lukasmwerner/mark/store"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter is the part of a bookmark that is written above the
+// description when editing a bookmark as text.
+type frontMatter struct {
+	Title string   `yaml:"title" toml:"title"`
+	Url   string   `yaml:"url" toml:"url"`
+	Tags  []string `yaml:"tags" toml:"tags"`
+}
+
+// frontMatterDelimiters follow the usual static site generator conventions of
+// --- for yaml and +++ for toml.
+var frontMatterDelimiters = map[string]string{
+	"yaml": "---",
+	"toml": "+++",
+}
+
+func encodeFrontMatter(format string, bookmark store.Bookmark) ([]byte, error) {
+	delimiter, ok := frontMatterDelimiters[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown front matter format: %s", format)
+	}
+
+	fm := frontMatter{Title: bookmark.Title, Url: bookmark.Url, Tags: bookmark.Tags}
+
+	var buf bytes.Buffer
+	buf.WriteString(delimiter + "\n")
+	switch format {
+	case "yaml":
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(fm); err != nil {
+			return nil, err
+		}
+		enc.Close()
+	case "toml":
+		if err := toml.NewEncoder(&buf).Encode(fm); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString(delimiter + "\n")
+	if bookmark.Description != "" {
+		// the newline ending the file is taken off again when decoding
+		buf.WriteString(bookmark.Description + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeFrontMatter parses text written by encodeFrontMatter back into
+// bookmark, keeping any fields that aren't part of the text as they were.
+func decodeFrontMatter(format string, text []byte, bookmark store.Bookmark) (store.Bookmark, error) {
+	delimiter, ok := frontMatterDelimiters[format]
+	if !ok {
+		return bookmark, fmt.Errorf("unknown front matter format: %s", format)
+	}
+
+	content := strings.ReplaceAll(string(text), "\r\n", "\n")
+	if !strings.HasPrefix(content, delimiter+"\n") {
+		return bookmark, fmt.Errorf("missing opening %s", delimiter)
+	}
+	content = strings.TrimPrefix(content, delimiter+"\n")
+
+	header, body, found := strings.Cut(content, "\n"+delimiter+"\n")
+	if !found {
+		header, found = strings.CutSuffix(content, "\n"+delimiter)
+		if !found {
+			return bookmark, fmt.Errorf("missing closing %s", delimiter)
+		}
+	}
+
+	var fm frontMatter
+	switch format {
+	case "yaml":
+		if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+			return bookmark, err
+		}
+	case "toml":
+		if _, err := toml.Decode(header, &fm); err != nil {
+			return bookmark, err
+		}
+	}
+
+	// fields are only replaced when they were edited, so saving the text
+	// unchanged leaves the bookmark as it was
+	if title := strings.TrimSpace(fm.Title); title != strings.TrimSpace(bookmark.Title) {
+		bookmark.Title = title
+	}
+	if url := strings.TrimSpace(fm.Url); url != strings.TrimSpace(bookmark.Url) {
+		bookmark.Url = url
+	}
+	if tags := store.NormalizeTags(fm.Tags); !slices.Equal(tags, store.NormalizeTags(bookmark.Tags)) {
+		bookmark.Tags = tags
+	}
+	bookmark.Description = strings.TrimSuffix(body, "\n")
+	return bookmark, nil
+}
+
+// editInEditor writes bookmark to a temporary file, opens it in the user's
+// $VISUAL or $EDITOR and parses the result once the editor exits. If the file
+// can't be parsed it is left behind so the changes aren't lost.
+func editInEditor(format string, bookmark store.Bookmark) (store.Bookmark, error) {
+	text, err := encodeFrontMatter(format, bookmark)
+	if err != nil {
+		return bookmark, err
+	}
+
+	f, err := os.CreateTemp("", fmt.Sprintf("mark-%d-*.md", bookmark.Id))
+	if err != nil {
+		return bookmark, err
+	}
+	_, err = f.Write(text)
+	f.Close()
+	if err != nil {
+		return bookmark, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	argv := append(strings.Fields(editor), f.Name())
+
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return bookmark, errors.Join(fmt.Errorf("editor exited with an error, edits kept in %s", f.Name()), err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return bookmark, err
+	}
+
+	updated, err := decodeFrontMatter(format, edited, bookmark)
+	if err != nil {
+		return bookmark, errors.Join(fmt.Errorf("unable to parse edited bookmark, edits kept in %s", f.Name()), err)
+	}
+	os.Remove(f.Name())
+
+	return updated, nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestFrontMatterUnchanged(t *testing.T) {
+	bookmarks := []store.Bookmark{
+		{Id: 1, Title: "Example", Url: "https://example.com", Tags: []string{"b", "a"}, Description: "  indented\n\nand a trailing newline\n"},
+		{Id: 2, Title: " padded ", Url: "https://example.com/2", Description: "no newline"},
+		{Id: 3, Url: "https://example.com/3"},
+	}
+	for format := range frontMatterDelimiters {
+		for _, bookmark := range bookmarks {
+			text, err := encodeFrontMatter(format, bookmark)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := decodeFrontMatter(format, text, bookmark)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, bookmark) {
+				t.Errorf("%s: saving unchanged text gave %+v, want %+v", format, decoded, bookmark)
+			}
+		}
+	}
+}
+
+func TestFrontMatterEdited(t *testing.T) {
+	bookmark := store.Bookmark{Id: 1, Title: "Example", Url: "https://example.com", Description: "old"}
+	text := []byte("---\ntitle: '  New title '\nurl: https://example.com\ntags: [Go, go]\n---\nnew\n")
+	decoded, err := decodeFrontMatter("yaml", text, bookmark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Title != "New title" || decoded.Description != "new" {
+		t.Errorf("got title %q and description %q", decoded.Title, decoded.Description)
+	}
+	if !reflect.DeepEqual(decoded.Tags, store.NormalizeTags([]string{"Go", "go"})) {
+		t.Errorf("got tags %q", decoded.Tags)
+	}
+}
@@ -0,0 +1,428 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lukasmwerner/mark/feeds"
+	"github.com/lukasmwerner/mark/importer"
+	"github.com/lukasmwerner/mark/rules"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var importTags []string
+var importEnrich bool
+var importDryRun bool
+var importVerbose bool
+var importNoFolders bool
+var importProfile string
+var importIncremental bool
+var importNewIds bool
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Imports bookmarks from browsers and other bookmark managers",
+	Long: `Imports bookmarks exported from browsers and other bookmark managers.
+
+Bookmarks that are already saved (by canonical url) are skipped, the rules in
+rules.toml are applied, and the whole import is saved in a single transaction
+so it syncs as one batch. Imported bookmarks keep their titles and
+descriptions, use --enrich to have mark enrich fetch the rest later.`,
+}
+
+// importHTMLCmd represents the import html command
+var importHTMLCmd = &cobra.Command{
+	Use:   "html <file>",
+	Short: "Imports a bookmarks.html file exported by a browser",
+	Long: `Imports the Netscape bookmarks.html format that every browser can export.
+
+The names of the folders a bookmark is in become its tags, except for the
+browser's own folders like the bookmarks toolbar, use --no-folders to leave
+them out. TAGS attributes, descriptions and the dates bookmarks were added
+and last changed are kept.
+
+Example:
+mark import html ~/Downloads/bookmarks.html --tags imported
+mark import html - < bookmarks.html --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		r, err := openImportFile(args[0])
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		bookmarks, err := importer.ParseNetscape(r, importer.ParseOptions{FolderTags: !importNoFolders})
+		if err != nil {
+			return errors.Join(errors.New("unable to parse "+args[0]), err)
+		}
+		return saveImport(bookmarks, "", nil)
+	},
+}
+
+// importJSONCmd represents the import json command
+var importJSONCmd = &cobra.Command{
+	Use:   "json <file>",
+	Short: "Imports or restores a json export of mark",
+	Long: `Imports the json documents and json lines written by mark export json.
+
+Bookmarks are restored under the ids they were exported with: ones already in
+the store are replaced by the exported version and the rest are added back
+with their timestamps, so restoring a backup into an existing store doesn't
+make duplicates. Rules aren't applied. With --new-ids the bookmarks are
+imported like any other export instead, getting new ids and skipping urls
+that are already bookmarked.
+
+Example:
+mark export json -o backup.json
+mark import json backup.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		r, err := openImportFile(args[0])
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		if !importNewIds {
+			return restoreImport(r, args[0])
+		}
+		bookmarks, err := (&importer.JSON{}).Parse(r, importer.ParseOptions{})
+		if err != nil {
+			return errors.Join(errors.New("unable to parse "+args[0]), err)
+		}
+		return saveImport(bookmarks, "", nil)
+	},
+}
+
+// importOPMLCmd represents the import opml command
+var importOPMLCmd = &cobra.Command{
+	Use:   "opml <file>",
+	Short: "Subscribes to the feeds of an OPML file",
+	Long: `Subscribes to the feeds listed in an OPML file, which every feed reader can
+export, for mark feeds poll to bookmark their new items.
+
+The folders the feeds are in become tags of their items, unless --no-folders
+is given, as do the tags given with --tags. Feeds that are subscribed to
+already are skipped.
+
+Example:
+mark import opml subscriptions.opml
+mark feeds poll`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		r, err := openImportFile(args[0])
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		subscriptions, err := feeds.ParseOPML(r, !importNoFolders)
+		if err != nil {
+			return errors.Join(errors.New("unable to parse "+args[0]), err)
+		}
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		added, subscribed, invalid := 0, 0, 0
+		err = store.Transaction(db, func(tx *store.Tx) error {
+			for _, feed := range subscriptions {
+				link, err := store.NormalizeURL(feed.Url)
+				if err != nil {
+					invalid++
+					if importVerbose {
+						fmt.Fprintln(os.Stderr, "skipped", err)
+					}
+					continue
+				}
+				feed.Url = link
+				feed.Tags = store.MergeTags(feed.Tags, importTags)
+				err = store.AddFeed(tx, feed)
+				if errors.Is(err, store.ErrSubscribed) {
+					subscribed++
+					if importVerbose {
+						fmt.Fprintln(os.Stderr, "skipped", link, "which is subscribed to already")
+					}
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				added++
+			}
+			if importDryRun {
+				return errImportDryRun
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errImportDryRun) {
+			return err
+		}
+
+		verb := "Subscribed to"
+		if importDryRun {
+			verb = "Would subscribe to"
+		}
+		fmt.Printf("%s %d of %d feeds, skipped %d already subscribed to and %d invalid\n",
+			verb, added, len(subscriptions), subscribed, invalid)
+		if added > 0 && !importDryRun {
+			fmt.Println("Run mark feeds poll to bookmark their new items")
+		}
+		return nil
+	},
+}
+
+// errImportDryRun rolls back a dry run.
+var errImportDryRun = errors.New("dry run")
+
+// importFirefoxCmd represents the import firefox command
+var importFirefoxCmd = &cobra.Command{
+	Use:   "firefox",
+	Short: "Imports the bookmarks of a Firefox profile",
+	Long: `Imports the bookmarks straight from the places.sqlite of a Firefox profile,
+which can be read while Firefox is running.
+
+Firefox tags and keywords become tags, as do the folders bookmarks are in
+unless --no-folders is given. The default profile is used unless another
+profile directory is given with --profile.
+
+With --incremental only the bookmarks that weren't imported from the same
+profile on this device before are imported, going by the ids the browser
+keeps for them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		profile := importProfile
+		if profile == "" {
+			var err error
+			if profile, err = importer.FirefoxProfile(); err != nil {
+				return err
+			}
+		}
+
+		bookmarks, guids, err := importer.ReadFirefox(profile, importer.ParseOptions{FolderTags: !importNoFolders})
+		if err != nil {
+			return err
+		}
+		return saveImport(bookmarks, "firefox:"+absPath(profile), guids)
+	},
+}
+
+// importChromeCmd represents the import chrome command
+var importChromeCmd = &cobra.Command{
+	Use:   "chrome",
+	Short: "Imports the bookmarks of a Chrome or Chromium profile",
+	Long: `Imports the bookmarks straight from the Bookmarks file of a Chrome, Chromium
+or Brave profile.
+
+The folders bookmarks are in become tags unless --no-folders is given. The
+default profile of the first browser found is used unless another profile
+directory (or Bookmarks file) is given with --profile.
+
+With --incremental only the bookmarks that weren't imported from the same
+profile on this device before are imported, going by the ids the browser
+keeps for them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		profile := importProfile
+		if profile == "" {
+			var err error
+			if profile, err = importer.ChromeProfile(); err != nil {
+				return err
+			}
+		}
+
+		bookmarks, guids, err := importer.ReadChrome(profile, importer.ParseOptions{FolderTags: !importNoFolders})
+		if err != nil {
+			return err
+		}
+		return saveImport(bookmarks, "chrome:"+absPath(profile), guids)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importHTMLCmd)
+	importCmd.AddCommand(importJSONCmd)
+	importCmd.AddCommand(importOPMLCmd)
+	importCmd.AddCommand(importFirefoxCmd)
+	importCmd.AddCommand(importChromeCmd)
+	for _, format := range importer.Registered() {
+		if !hasSubcommand(importCmd, format.Name()) {
+			importCmd.AddCommand(importFormatCmd(format))
+		}
+	}
+
+	importCmd.PersistentFlags().StringSliceVar(&importTags, "tags", []string{}, "Tags added to every imported bookmark")
+	importCmd.PersistentFlags().BoolVar(&importEnrich, "enrich", false, "Queue the bookmarks for mark enrich to fetch their metadata")
+	importCmd.PersistentFlags().BoolVarP(&importDryRun, "dry-run", "n", false, "Only count what would be imported")
+	importCmd.PersistentFlags().BoolVarP(&importVerbose, "verbose", "v", false, "List every bookmark that is skipped")
+	importCmd.PersistentFlags().BoolVar(&importNoFolders, "no-folders", false, "Don't tag bookmarks with the folders they are in")
+	importJSONCmd.Flags().BoolVar(&importNewIds, "new-ids", false, "Add the bookmarks as new ones instead of restoring their ids")
+	for _, browser := range []*cobra.Command{importFirefoxCmd, importChromeCmd} {
+		browser.Flags().StringVar(&importProfile, "profile", "", "The browser profile directory to import from")
+		browser.Flags().BoolVar(&importIncremental, "incremental", false, "Only import bookmarks that weren't imported from the profile before")
+	}
+}
+
+// importFormatCmd makes the import command of a format that has no command of
+// its own.
+func importFormatCmd(format importer.Format) *cobra.Command {
+	return &cobra.Command{
+		Use:   format.Name() + " <file>",
+		Short: format.Description(),
+		Long: format.Description() + `.
+
+Tags, notes, read status and the dates bookmarks were added are kept where
+the export has them. Use - to read the export from stdin.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			r, err := openImportFile(args[0])
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			bookmarks, err := format.Parse(r, importer.ParseOptions{FolderTags: !importNoFolders})
+			if err != nil {
+				return errors.Join(errors.New("unable to parse "+args[0]), err)
+			}
+			return saveImport(bookmarks, "", nil)
+		},
+	}
+}
+
+func hasSubcommand(cmd *cobra.Command, name string) bool {
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// openImportFile opens the file to import, or stdin for -.
+func openImportFile(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Join(errors.New("unable to open "+name), err)
+	}
+	return f, nil
+}
+
+// saveImport saves the parsed bookmarks and reports what happened. source
+// names the browser profile for incremental imports, and ids are the ids the
+// browser keeps for the bookmarks.
+func saveImport(bookmarks []store.Bookmark, source string, ids []string) error {
+	db, err := store.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	r, err := rules.Load(db.StoreLoc)
+	if err != nil {
+		return err
+	}
+
+	result, err := importer.Save(db, bookmarks, importer.Options{
+		Rules:       r,
+		Tags:        importTags,
+		Enrich:      importEnrich,
+		DryRun:      importDryRun,
+		Source:      source,
+		SourceIds:   ids,
+		Incremental: importIncremental,
+	})
+	if err != nil {
+		return err
+	}
+
+	printSkipped(result)
+	verb := "Imported"
+	if importDryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d of %d bookmarks, skipped %d already bookmarked and %d invalid\n",
+		verb, result.Added, len(bookmarks), result.Duplicates, result.Invalid)
+	if result.Seen > 0 {
+		fmt.Printf("%d were imported before and left out\n", result.Seen)
+	}
+	if importEnrich && !importDryRun && result.Added > 0 {
+		fmt.Println("Run mark enrich to fetch their metadata")
+	}
+	return nil
+}
+
+// restoreImport restores the bookmarks of a mark json export under their ids,
+// reading them one at a time.
+func restoreImport(r io.Reader, name string) error {
+	db, err := store.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	result, err := importer.RestoreJSON(db, r, importer.Options{
+		Tags:   importTags,
+		Enrich: importEnrich,
+		DryRun: importDryRun,
+	})
+	if err != nil {
+		return errors.Join(errors.New("unable to restore "+name), err)
+	}
+	total := result.Added + result.Updated + result.Unchanged + result.Duplicates + result.Invalid
+
+	printSkipped(result)
+	verb := "Restored"
+	if importDryRun {
+		verb = "Would restore"
+	}
+	fmt.Printf("%s %d of %d bookmarks, added %d and updated %d, %d were unchanged\n",
+		verb, result.Added+result.Updated, total, result.Added, result.Updated, result.Unchanged)
+	if result.Duplicates > 0 || result.Invalid > 0 {
+		fmt.Printf("skipped %d bookmarked under another id and %d invalid\n", result.Duplicates, result.Invalid)
+	}
+	return nil
+}
+
+func printSkipped(result importer.Result) {
+	if importVerbose {
+		for _, skipped := range result.Skipped {
+			fmt.Fprintln(os.Stderr, "skipped", skipped)
+		}
+	}
+}
+
+func absPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return name
+}
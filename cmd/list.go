@@ -0,0 +1,52 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var listOutput outputFlags
+var listTags []string
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list [query]",
+	Short: "Lists bookmarks",
+	Long: `Lists every bookmark, or the ones matching a search query, without
+prompting. Use --tag to only list bookmarks with the tag, given more than
+once a bookmark needs every one of them.
+
+By default every bookmark is printed on a line as its id, title and url
+separated by tabs, which is the template mode. --template changes the line
+and --mode picks one of the other formats of mark show.
+
+Example:
+mark list --tag golang
+mark list -m markdown rust
+mark list --template '{{.Url}}' | xargs -n1 curl -sI`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		bookmarks, err := exportBookmarks(db, args)
+		if err != nil {
+			return err
+		}
+		return listOutput.write(db, filterTags(bookmarks, listTags))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listOutput.register(listCmd, "template")
+	listCmd.Flags().StringSliceVar(&listTags, "tag", []string{}, "Only list bookmarks with the tag")
+}
@@ -4,9 +4,8 @@ Copyright © 2024 Lukas Werner <me@lukaswerner.com>
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/cli/browser"
@@ -14,52 +13,43 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var openSelection selection
+
 // openCmd represents the open command
 var openCmd = &cobra.Command{
 	Use:   "open",
 	Short: "Opens the matching search link in the user's browser",
-	Long:  ``,
-	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Opens the bookmark matching the search query in the user's browser.
+
+When multiple bookmarks match you will be prompted to pick one, unless
+--first, --all, --index or --id decide for you. Without a terminal an
+ambiguous match exits with status 3 and no match with status 2. An --index
+past the matches exits with status 4.`,
+	Args: openSelection.args,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
 		db, err := store.Open()
 		if err != nil {
-			log.Panicln(err.Error())
-			return
+			return err
 		}
 		defer db.Close()
 
-		searchQuery := strings.Join(args, " ")
-
-		bookmarks, err := store.SearchBookmarks(db, searchQuery)
-		if err != nil {
-			log.Panicln("unable to search bookmarks", err.Error())
-		}
-		if len(bookmarks) == 0 {
-			fmt.Println("found no bookmarks")
-			return
+		bookmarks, err := openSelection.resolve(db, args)
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil
 		}
-
-		if len(bookmarks) == 1 {
-			fmt.Printf("Opening %s %s\n", bookmarks[0].Title, bookmarks[0].Url)
-			browser.OpenURL(bookmarks[0].Url)
-			return
+		if err != nil {
+			return err
 		}
 
-		pickedLink := ""
-		options := make([]huh.Option[string], len(bookmarks))
-		for i, bookmark := range bookmarks {
-			options[i] = huh.NewOption(bookmark.Title, bookmark.Url)
-		}
-		err = huh.NewSelect[string]().Title("Pick your link").Options(options...).Value(&pickedLink).Run()
-		if err != nil {
-			if err == huh.ErrUserAborted {
-				return
+		for _, bookmark := range bookmarks {
+			fmt.Printf("Opening %s %s\n", bookmark.Title, bookmark.Url)
+			if err := browser.OpenURL(bookmark.Url); err != nil {
+				return err
 			}
-			log.Fatalln(err.Error())
 		}
-
-		browser.OpenURL(pickedLink)
-
+		return nil
 	},
 }
 
@@ -75,4 +65,5 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// openCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	openSelection.register(openCmd)
 }
@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var restoreConfig bool
+var restoreDryRun bool
+var restoreYes bool
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup>",
+	Short: "Restores the bookmarks of a backup",
+	Long: `Makes the store hold the bookmarks of a backup again: bookmarks are put
+back as they were, and ones added since are deleted. The feeds and the state
+of incremental imports are restored too, and with --config also config.toml
+and rules.toml.
+
+The backup is a file made with mark backup, the name of one in the backups
+directory as listed by mark backup list, or latest for the newest one.
+
+The database isn't replaced by the one in the backup, so it keeps its
+cr-sqlite site id. The restored bookmarks are saved as new changes instead,
+which sync to your other devices and win over what they have.
+
+A backup of the store is taken before restoring, so a restore can be undone
+with another one.
+
+Example:
+mark restore latest --dry-run
+mark restore ~/Dropbox/mark.tar.gz --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		archive, err := findBackup(db, args[0])
+		if err != nil {
+			return err
+		}
+
+		preview, err := store.RestoreBackup(db, archive, store.RestoreOptions{DryRun: true})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restoring adds %d, updates %d and deletes %d bookmarks, %d are unchanged\n",
+			preview.Added, preview.Updated, preview.Deleted, preview.Unchanged)
+		if restoreDryRun {
+			return nil
+		}
+		if preview.Added+preview.Updated+preview.Deleted == 0 && !restoreConfig {
+			fmt.Println("Nothing to restore")
+			return nil
+		}
+		if !restoreYes {
+			if !isInteractive() {
+				return errors.New("not attached to a terminal, use --yes to restore the backup")
+			}
+			confirmed := false
+			err := huh.NewConfirm().Title("Restore " + filepath.Base(archive) + "?").Value(&confirmed).Run()
+			if err != nil && !errors.Is(err, huh.ErrUserAborted) {
+				return err
+			}
+			if !confirmed {
+				return nil
+			}
+		}
+
+		backup, err := store.AutoBackupBeforeRestore(db, archive)
+		if err != nil {
+			return err
+		}
+		result, err := store.RestoreBackup(db, archive, store.RestoreOptions{Config: restoreConfig})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added %d, updated %d and deleted %d bookmarks\n", result.Added, result.Updated, result.Deleted)
+		if backup.Path != "" {
+			fmt.Println("The bookmarks from before are in " + backup.Path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolVar(&restoreConfig, "config", false, "Also restore config.toml and rules.toml")
+	restoreCmd.Flags().BoolVarP(&restoreDryRun, "dry-run", "n", false, "Only count what would change")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Restore without asking")
+}
+
+// findBackup resolves the backup given to mark restore to its file.
+func findBackup(db *store.DB, name string) (string, error) {
+	if name == "latest" {
+		backups, err := store.ListBackups(db)
+		if err != nil {
+			return "", err
+		}
+		if len(backups) == 0 {
+			return "", errors.New("there are no backups in " + db.BackupDir())
+		}
+		return backups[0].Path, nil
+	}
+
+	candidates := []string{name}
+	if !strings.ContainsRune(name, os.PathSeparator) {
+		candidates = append(candidates,
+			filepath.Join(db.BackupDir(), name),
+			filepath.Join(db.BackupDir(), name+".tar.gz"))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("found no backup " + name + ", see mark backup list")
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/rules"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var retagRules bool
+var retagDryRun bool
+var retagYes bool
+
+// retagCmd represents the retag command
+var retagCmd = &cobra.Command{
+	Use:   "retag",
+	Short: "Tags existing bookmarks using the rules in rules.toml",
+	Long: `Applies the auto-tagging rules from rules.toml in the store location to
+every bookmark, adding the tags of each rule that matches. Tags are only ever
+added, never removed.
+
+The rules are also applied by mark add and when importing. For example:
+
+[[rule]]
+tags = ["go"]
+domains = ["go.dev", "golang.org"]
+
+[[rule]]
+tags = ["video"]
+urls = ['youtube\.com/watch', 'vimeo\.com/\d+']
+
+[[rule]]
+tags = ["ml"]
+titles = ['(?i)neural']
+keywords = ["machine learning"]
+
+A rule matches when every kind of condition it has (domains, urls, titles,
+keywords) matches at least once. urls and titles are regular expressions,
+keywords are the ones scraped from the page.
+
+Example:
+mark retag --rules --dry-run
+mark retag --rules --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if !retagRules {
+			return errors.New("nothing to do, use --rules to apply rules.toml")
+		}
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		r, err := rules.Load(db.StoreLoc)
+		if err != nil {
+			return err
+		}
+		if len(r.Rules) == 0 {
+			return errors.New("there are no rules in rules.toml")
+		}
+
+		bookmarks, err := store.ListBookmarks(db)
+		if err != nil {
+			return errors.Join(errors.New("unable to list bookmarks"), err)
+		}
+
+		changed := []store.Bookmark{}
+		for _, bookmark := range bookmarks {
+			added := r.Tags(bookmark)
+			if len(added) == 0 {
+				continue
+			}
+			fmt.Printf("%d\t%s\t%s\n\t+ %s\n", bookmark.Id, bookmark.Title, bookmark.Url, strings.Join(added, ", "))
+			changed = append(changed, bookmark)
+		}
+
+		if len(changed) == 0 {
+			fmt.Printf("No new tags for any of the %d bookmarks\n", len(bookmarks))
+			return nil
+		}
+		fmt.Printf("%d of %d bookmarks would gain tags\n", len(changed), len(bookmarks))
+
+		if retagDryRun {
+			return nil
+		}
+		if !retagYes {
+			if !isInteractive() {
+				return errors.New("not attached to a terminal, use --yes to apply the tags")
+			}
+			confirmed := false
+			err := huh.NewConfirm().Title(fmt.Sprintf("Tag %d bookmarks?", len(changed))).Value(&confirmed).Run()
+			if err != nil && !errors.Is(err, huh.ErrUserAborted) {
+				return err
+			}
+			if !confirmed {
+				return nil
+			}
+		}
+
+		if _, err := store.AutoBackup(db, "retag"); err != nil {
+			return err
+		}
+
+		err = store.Transaction(db, func(tx *store.Tx) error {
+			for _, bookmark := range changed {
+				if err := store.UpdateBookmark(tx, bookmark, r.Apply(bookmark)); err != nil {
+					return errors.Join(fmt.Errorf("unable to tag bookmark %d", bookmark.Id), err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Tagged %d bookmarks\n", len(changed))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retagCmd)
+
+	retagCmd.Flags().BoolVar(&retagRules, "rules", false, "Add the tags from the rules in rules.toml")
+	retagCmd.Flags().BoolVarP(&retagDryRun, "dry-run", "n", false, "Only show which bookmarks would gain which tags")
+	retagCmd.Flags().BoolVarP(&retagYes, "yes", "y", false, "Apply the tags without asking")
+}
@@ -4,16 +4,22 @@ Copyright © 2024 Lukas Werner <me@lukaswerner.com>
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"net/url"
 	"os"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
 	"github.com/cli/browser"
+	"github.com/lukasmwerner/mark/enrich"
 	"github.com/lukasmwerner/mark/store"
 	"github.com/spf13/cobra"
 )
@@ -70,6 +76,27 @@ type rootAppModel struct {
 
 func (m rootAppModel) Init() tea.Cmd { return nil }
 
+type enrichedMsg struct {
+	count int
+}
+
+// enrichPending fetches the metadata of bookmarks that were added while
+// offline, until ctx is canceled.
+func enrichPending(ctx context.Context, db *store.DB) enrichedMsg {
+	runner, err := enrich.NewRunner(db)
+	if err != nil {
+		return enrichedMsg{}
+	}
+
+	count := 0
+	runner.Pending(ctx, false, func(result enrich.Result) {
+		if result.Err == nil {
+			count++
+		}
+	})
+	return enrichedMsg{count: count}
+}
+
 func (m rootAppModel) updateTable() rootAppModel {
 
 	bookmarks, err := store.SearchBookmarks(m.db, m.input.Value())
@@ -88,7 +115,7 @@ func (m rootAppModel) updateTable() rootAppModel {
 	m.currentIndex = 1
 
 	for _, bookmark := range bookmarks {
-		m.table.Row(strings.TrimSpace(bookmark.Title), bookmark.Description, strings.Join(bookmark.Tags, ","), bookmark.Url)
+		m.table.Row(domainGlyph(bookmark.Url), strings.TrimSpace(bookmark.Title), bookmark.Description, strings.Join(bookmark.Tags, ","), bookmark.Url)
 	}
 
 	return m
@@ -97,6 +124,10 @@ func (m rootAppModel) updateTable() rootAppModel {
 func (m rootAppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	switch msg := msg.(type) {
+	case enrichedMsg:
+		if msg.count > 0 && m.rowsCount > 0 && m.mode == NORMAL {
+			m = m.updateTable()
+		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -192,6 +223,8 @@ func (m rootAppModel) View() string {
 			return selectedStyle
 		case row == m.currentIndex && m.mode == SEARCH:
 			return unactiveSelectedStyle
+		case col == 0 && row <= len(m.rows):
+			return lipgloss.NewStyle().Bold(true).Foreground(glyphColor(m.rows[row-1].Url))
 		default:
 			return lipgloss.NewStyle()
 		}
@@ -212,6 +245,31 @@ func (m rootAppModel) View() string {
 	return lipgloss.JoinVertical(lipgloss.Left, m.input.View(), table, statusBar)
 }
 
+// domainGlyph is the first letter of the bookmark's domain, standing in for
+// its favicon.
+func domainGlyph(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "·"
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	r, _ := utf8.DecodeRuneInString(host)
+	return strings.ToUpper(string(r))
+}
+
+var glyphColors = []lipgloss.Color{"39", "42", "170", "208", "220", "203", "75", "141", "114", "180"}
+
+// glyphColor gives each domain its own color, the same one every time.
+func glyphColor(rawURL string) lipgloss.Color {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return glyphColors[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")))
+	return glyphColors[h.Sum32()%uint32(len(glyphColors))]
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "mark",
@@ -241,11 +299,22 @@ file sync service. This is sort-of explained the following blog post:
 
 		t.Border(lipgloss.NormalBorder())
 
-		t.Headers("Title", "Description", "Tags", "URL")
+		t.Headers("", "Title", "Description", "Tags", "URL")
 
 		prog := tea.NewProgram(m, tea.WithAltScreen())
 
-		if _, err := prog.Run(); err != nil {
+		// enrich in the background, and stop before the database is closed
+		ctx, cancel := context.WithCancel(cmd.Context())
+		enriched := make(chan struct{})
+		go func() {
+			defer close(enriched)
+			prog.Send(enrichPending(ctx, db))
+		}()
+
+		_, err = prog.Run()
+		cancel()
+		<-enriched
+		if err != nil {
 			fmt.Println("Error running program:", err)
 			os.Exit(1)
 		}
@@ -257,6 +326,10 @@ file sync service. This is sort-of explained the following blog post:
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
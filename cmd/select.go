@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes used when a search can't be narrowed down to the bookmarks a
+// command should act on, so scripts can tell the cases apart.
+const (
+	exitNoMatch    = 2
+	exitAmbiguous  = 3
+	exitOutOfRange = 4
+)
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// selection holds the flags shared by commands that search for bookmarks and
+// then act on one (or all) of the results.
+type selection struct {
+	first bool
+	all   bool
+	index int
+	id    int64
+}
+
+func (s *selection) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&s.first, "first", false, "Use the first matching bookmark instead of prompting")
+	cmd.Flags().BoolVar(&s.all, "all", false, "Use every matching bookmark")
+	cmd.Flags().IntVar(&s.index, "index", 0, "Use the Nth matching bookmark (starting at 1)")
+	cmd.Flags().Int64Var(&s.id, "id", 0, "Use the bookmark with this id instead of searching")
+	cmd.MarkFlagsMutuallyExclusive("first", "all", "index", "id")
+}
+
+// args requires a search query unless a bookmark was picked directly with
+// --id, which can't be combined with one.
+func (s *selection) args(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("id") {
+		if len(args) > 0 {
+			return fmt.Errorf("--id picks the bookmark without searching, leave out the query %q", strings.Join(args, " "))
+		}
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// resolve searches for the bookmarks matching args and narrows them down
+// according to the selection flags. When several bookmarks match and no flag
+// decides between them the user is prompted, unless we aren't attached to a
+// terminal, in which case an ambiguous match error is returned instead.
+func (s *selection) resolve(db *store.DB, args []string) ([]store.Bookmark, error) {
+	if s.id != 0 {
+		bookmark, err := store.GetBookmark(db, store.BookmarkId(s.id))
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, &exitError{exitNoMatch, fmt.Errorf("found no bookmark with id %d", s.id)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []store.Bookmark{bookmark}, nil
+	}
+
+	searchQuery := strings.Join(args, " ")
+
+	bookmarks, err := store.SearchBookmarks(db, searchQuery)
+	if err != nil {
+		return nil, errors.Join(errors.New("unable to search bookmarks"), err)
+	}
+	if len(bookmarks) == 0 {
+		return nil, &exitError{exitNoMatch, errors.New("found no bookmarks")}
+	}
+
+	switch {
+	case s.all:
+		return bookmarks, nil
+	case s.first:
+		return bookmarks[:1], nil
+	case s.index != 0:
+		if s.index < 1 || s.index > len(bookmarks) {
+			return nil, &exitError{exitOutOfRange, fmt.Errorf("--index %d is out of range, %d bookmarks match", s.index, len(bookmarks))}
+		}
+		return bookmarks[s.index-1 : s.index], nil
+	case len(bookmarks) == 1:
+		return bookmarks, nil
+	}
+
+	if !isInteractive() {
+		for i, bookmark := range bookmarks {
+			fmt.Fprintf(os.Stderr, "%d\t%d\t%s\t%s\n", i+1, bookmark.Id, bookmark.Title, bookmark.Url)
+		}
+		return nil, &exitError{exitAmbiguous, fmt.Errorf("found %d bookmarks, use --first, --all, --index or --id to pick", len(bookmarks))}
+	}
+
+	pickedIndex := 0
+	options := make([]huh.Option[int], len(bookmarks))
+	for i, bookmark := range bookmarks {
+		options[i] = huh.NewOption(bookmark.Title, i)
+	}
+	err = huh.NewSelect[int]().Title("Pick your link").Options(options...).Value(&pickedIndex).Run()
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks[pickedIndex : pickedIndex+1], nil
+}
+
+// isInteractive reports whether both stdin and stdout are attached to a
+// terminal, which is required before showing any huh prompts.
+func isInteractive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSelectionArgs(t *testing.T) {
+	tests := []struct {
+		flags []string
+		ok    bool
+	}{
+		{[]string{"golang"}, true},
+		{[]string{}, false},
+		{[]string{"--id", "3"}, true},
+		{[]string{"--id", "3", "golang"}, false},
+	}
+	for _, test := range tests {
+		s := &selection{}
+		cmd := &cobra.Command{Use: "test", Args: s.args, RunE: func(*cobra.Command, []string) error { return nil }}
+		s.register(cmd)
+		cmd.SetArgs(test.flags)
+		cmd.SilenceErrors, cmd.SilenceUsage = true, true
+		if err := cmd.Execute(); (err == nil) != test.ok {
+			t.Errorf("%q: got %v", test.flags, err)
+		}
+	}
+}
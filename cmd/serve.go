@@ -0,0 +1,167 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/store"
+	"github.com/spf13/cobra"
+)
+
+var serveAddr string
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves feeds of the bookmarks over http",
+	Long: `Serves the newest bookmarks as feeds for feed readers to follow:
+
+  /feed.atom  an Atom feed
+  /feed.rss   an RSS 2.0 feed
+
+Both take ?tag= to only include bookmarks with the tag (given more than once
+a bookmark needs every one of them), ?q= to search and ?limit= for how many
+bookmarks to include. The feeds are the same as mark export feed writes.
+
+Bookmarks synced from other hosts show up without restarting. mark serve
+listens on localhost, use --addr :8080 to share the feeds with others.
+
+Example:
+mark serve
+curl 'http://localhost:8080/feed.atom?tag=golang'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		db, err := store.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		server := &http.Server{
+			Addr:              serveAddr,
+			Handler:           &feedServer{db: db, refreshed: time.Now()},
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		fmt.Printf("Serving feeds on http://%s/feed.atom\n", serveAddr)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "The address to listen on")
+}
+
+// refreshInterval is how often mark serve picks up changes synced from other
+// hosts.
+const refreshInterval = 30 * time.Second
+
+// feedServer serves the feeds of mark serve. The store is used by one
+// request at a time.
+type feedServer struct {
+	db *store.DB
+
+	mu        sync.Mutex
+	refreshed time.Time
+}
+
+func (s *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	formats := map[string]string{
+		"/feed.atom": exporter.FeedAtom,
+		"/feed.rss":  exporter.FeedRSS,
+	}
+	format, ok := formats[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultFeedLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	tags := query["tag"]
+
+	bookmarks, err := s.bookmarks(query.Get("q"))
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "unable to read the bookmarks", http.StatusInternalServerError)
+		return
+	}
+	bookmarks = filterTags(bookmarks, tags)
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	contentTypes := map[string]string{
+		exporter.FeedAtom: "application/atom+xml; charset=utf-8",
+		exporter.FeedRSS:  "application/rss+xml; charset=utf-8",
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	err = exporter.WriteFeed(w, format, bookmarks, exporter.FeedOptions{
+		Title: feedTitle(tags),
+		Self:  scheme + "://" + r.Host + r.URL.RequestURI(),
+		Limit: limit,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// bookmarks returns the bookmarks matching the search query, or all of them,
+// first picking up changes from other hosts when it is time to.
+func (s *feedServer) bookmarks(query string) ([]store.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.refreshed) > refreshInterval {
+		if err := s.db.Refresh(); err != nil {
+			log.Println("unable to sync from other hosts:", err)
+		}
+		s.refreshed = time.Now()
+	}
+	if query == "" {
+		return store.ListBookmarks(s.db)
+	}
+	return store.SearchBookmarks(s.db, query)
+}
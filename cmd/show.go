@@ -4,63 +4,62 @@ Copyright © 2024 Lukas Werner <me@lukaswerner.com>
 package cmd
 
 import (
-	"encoding/csv"
-	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
 	"os"
 	"strings"
 
 	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/output"
 	"github.com/lukasmwerner/mark/store"
 	"github.com/spf13/cobra"
 )
 
-var outputMode string
+var showOutput outputFlags
+var showSelection selection
 
 // showCmd represents the show command
 var showCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Shows the entry of a bookmark",
-	Long:  ``,
-	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Shows the entry of the bookmark matching the search query.
+
+When multiple bookmarks match you will be prompted to pick one, unless
+--first, --all, --index or --id decide for you. Without a terminal an
+ambiguous match exits with status 3 and no match with status 2. An --index
+past the matches exits with status 4.
+
+The fields are printed as json lines by default, --mode picks another
+format. With --mode template every bookmark is printed with the Go
+text/template given with --template, which has the functions of the export
+templates: domain, join, date, oneline, md, mdurl, org and orgurl. Templates
+see the Go names of the fields, like .Url and .CreatedAt, while the json,
+yaml and toml modes print the fields of mark export json under their
+lowercase names, like url and created_at. The markdown mode uses the
+markdown export template, yours if you have one.
+
+Example:
+mark show golang --all -m yaml
+mark show golang --all -m template --template '{{.Url}} {{join .Tags ","}}'`,
+	Args: showSelection.args,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
 		db, err := store.Open()
 		if err != nil {
-			log.Panicln(err.Error())
-			return
+			return err
 		}
 		defer db.Close()
 
-		searchQuery := strings.Join(args, " ")
-
-		bookmarks, err := store.SearchBookmarks(db, searchQuery)
-		if err != nil {
-			log.Panicln("unable to search bookmarks", err.Error())
-		}
-		if len(bookmarks) == 0 {
-			fmt.Println("found no bookmarks")
-			return
+		bookmarks, err := showSelection.resolve(db, args)
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil
 		}
-
-		if len(bookmarks) != 1 {
-			pickedIndex := 0
-			options := make([]huh.Option[int], len(bookmarks))
-			for i, bookmark := range bookmarks {
-				options[i] = huh.NewOption(bookmark.Title, i)
-			}
-			err = huh.NewSelect[int]().Title("Pick your link").Options(options...).Value(&pickedIndex).Run()
-			if err != nil {
-				if err == huh.ErrUserAborted {
-					return
-				}
-				log.Fatalln(err.Error())
-			}
-			bookmarks = []store.Bookmark{bookmarks[pickedIndex]}
+		if err != nil {
+			return err
 		}
 
-		outputBookmark(outputMode, bookmarks[0])
-		return
+		return showOutput.write(db, bookmarks)
 	},
 }
 
@@ -76,19 +75,34 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// showCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	showCmd.Flags().StringVarP(&outputMode, "mode", "m", "json", "Output mode: json,csv")
+	showOutput.register(showCmd, "json")
+	showSelection.register(showCmd)
 }
 
-func outputBookmark(mode string, bookmark store.Bookmark) {
-	switch mode {
-	case "json":
-		b, _ := json.Marshal(bookmark)
-		os.Stdout.Write(b)
-	case "csv":
-		w := csv.NewWriter(os.Stdout)
-		w.Write([]string{"Title", "Description", "Tags", "URL"})
-		w.Write([]string{bookmark.Title, bookmark.Description, strings.Join(bookmark.Tags, ","), bookmark.Url})
-		w.Flush()
+// outputFlags holds the flags of commands that print bookmarks in one of the
+// output formats.
+type outputFlags struct {
+	mode     string
+	template string
+}
 
+func (o *outputFlags) register(cmd *cobra.Command, mode string) {
+	cmd.Flags().StringVarP(&o.mode, "mode", "m", mode, "Output mode: "+strings.Join(output.Modes(), ","))
+	cmd.Flags().StringVar(&o.template, "template", output.DefaultTemplate, "The text/template printed for every bookmark with --mode template")
+}
+
+// write prints the bookmarks to stdout in the --mode format.
+func (o *outputFlags) write(db *store.DB, bookmarks []store.Bookmark) error {
+	formatter, err := output.Lookup(o.mode)
+	if err != nil {
+		return err
 	}
+	return formatter.Format(os.Stdout, bookmarks, output.Options{
+		Template:  o.template,
+		Templates: exporter.Templates(templatesDir(db)),
+	})
+}
+
+func printBookmark(bookmark store.Bookmark) {
+	output.PrintBookmark(os.Stdout, bookmark)
 }
@@ -0,0 +1,58 @@
+/*
+Copyright © 2024 Lukas Werner <me@lukaswerner.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// maxSuggestions is how many suggested tags are offered at once.
+const maxSuggestions = 12
+
+// suggestedTagsField returns a multi-select of the tags suggested for the
+// bookmark that stores the chosen ones in selected, or nil if there is
+// nothing to suggest.
+func suggestedTagsField(db *store.DB, bookmark store.Bookmark, selected *[]string) (*huh.MultiSelect[string], error) {
+	suggestions, err := store.SuggestTags(db, bookmark, maxSuggestions)
+	if err != nil || len(suggestions) == 0 {
+		return nil, err
+	}
+
+	options := []huh.Option[string]{}
+	for _, s := range suggestions {
+		label := fmt.Sprintf("%s (%s)", s.Tag, strings.Join(s.Reasons, ", "))
+		options = append(options, huh.NewOption(label, s.Tag))
+	}
+	return huh.NewMultiSelect[string]().
+		Title("Suggested tags").
+		Options(options...).
+		Value(selected), nil
+}
+
+// chooseSuggestedTags asks which of the suggested tags to add to a saved
+// bookmark and saves them.
+func chooseSuggestedTags(db *store.DB, bookmark store.Bookmark) (store.Bookmark, error) {
+	selected := []string{}
+	field, err := suggestedTagsField(db, bookmark, &selected)
+	if err != nil || field == nil {
+		return bookmark, err
+	}
+	if err := field.Run(); err != nil {
+		return bookmark, err
+	}
+	if len(selected) == 0 {
+		return bookmark, nil
+	}
+
+	updated := bookmark
+	updated.Tags = store.MergeTags(bookmark.Tags, selected)
+	if err := store.UpdateBookmark(db, bookmark, updated); err != nil {
+		return bookmark, err
+	}
+	return updated, nil
+}
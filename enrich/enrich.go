@@ -0,0 +1,352 @@
+// Package enrich fills in bookmarks with scraped metadata in the background,
+// working through the enrichment jobs recorded in the store.
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lukasmwerner/mark/rules"
+	"github.com/lukasmwerner/mark/scrape"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// PrimaryURLs are the choices for which url a bookmark is saved under.
+var PrimaryURLs = []string{"original", "resolved", "canonical", "linked"}
+
+// ValidPrimary returns an error if primary isn't one of PrimaryURLs.
+func ValidPrimary(primary string) error {
+	for _, p := range PrimaryURLs {
+		if p == primary {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown primary url %q, expected original, resolved, canonical or linked", primary)
+}
+
+// PrimaryURL picks the url a bookmark is saved under, falling back from the
+// canonical link, or the article a discussion links to, to the resolved url to
+// the original one when the page didn't provide them. Canonical links are
+// only used when trustCanonical allows it.
+func PrimaryURL(bm store.Bookmark, primary string) string {
+	switch primary {
+	case "linked":
+		if bm.LinkedUrl != "" {
+			return bm.LinkedUrl
+		}
+		if bm.ResolvedUrl != "" {
+			return bm.ResolvedUrl
+		}
+	case "canonical":
+		if trustCanonical(bm) {
+			return bm.CanonicalLink
+		}
+		fallthrough
+	case "resolved":
+		if bm.ResolvedUrl != "" {
+			return bm.ResolvedUrl
+		}
+	}
+	if bm.OriginalUrl != "" {
+		return bm.OriginalUrl
+	}
+	return bm.Url
+}
+
+// trustCanonical reports whether the page's canonical link can stand in for
+// where it was fetched from. Some sites point every page's canonical link at
+// their homepage or at another site, which would turn unrelated pages into
+// duplicates of each other, so it has to be on the same host (give or take
+// www.) and only point at the root if the page itself is the root.
+func trustCanonical(bm store.Bookmark) bool {
+	if bm.CanonicalLink == "" {
+		return false
+	}
+	canonical, err := url.Parse(bm.CanonicalLink)
+	if err != nil {
+		return false
+	}
+	page, err := url.Parse(firstURL(bm.ResolvedUrl, bm.OriginalUrl, bm.Url))
+	if err != nil {
+		return false
+	}
+	if hostname(canonical) != hostname(page) {
+		return false
+	}
+	return !isRoot(canonical) || isRoot(page)
+}
+
+func firstURL(urls ...string) string {
+	for _, u := range urls {
+		if u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+func hostname(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func isRoot(u *url.URL) bool {
+	return strings.Trim(u.Path, "/") == "" && u.RawQuery == ""
+}
+
+// Apply copies the scraped metadata onto a bookmark, leaving the fields the
+// user set by hand alone, and records where the url led.
+func Apply(bm store.Bookmark, md *scrape.Metadata, primary string) store.Bookmark {
+	if !bm.IsUserField("title") && md.Title != "" {
+		bm.Title = md.Title
+	}
+	if !bm.IsUserField("description") && md.Description != "" {
+		bm.Description = md.Description
+	}
+	bm.Tags = store.MergeTags(bm.Tags, md.Tags)
+	if len(md.Keywords) > 0 {
+		bm.Keywords = md.Keywords
+	}
+	if md.LinkedURL != "" {
+		bm.LinkedUrl = md.LinkedURL
+	}
+	if len(md.Authors) > 0 {
+		bm.Authors = md.Authors
+	}
+	if md.Stars > 0 {
+		bm.Stars = md.Stars
+	}
+	if md.Duration > 0 {
+		bm.Duration = md.Duration
+	}
+
+	if bm.OriginalUrl == "" {
+		bm.OriginalUrl = bm.Url
+	}
+	bm.ResolvedUrl = md.URL
+	bm.CanonicalLink = md.CanonicalURL
+	bm.Redirects = md.Redirects
+	if !bm.IsUserField("url") {
+		bm.Url = PrimaryURL(bm, primary)
+	}
+	return bm
+}
+
+// DefaultWorkers is how many pages a Runner fetches at the same time.
+const DefaultWorkers = 4
+
+// Runner fetches metadata for bookmarks and saves it, tagging them with the
+// user's rules along the way.
+type Runner struct {
+	DB     *store.DB
+	Client *scrape.Client
+	Rules  *rules.Rules
+	// Workers is how many pages Run fetches at the same time. Saving is
+	// always done one bookmark at a time.
+	Workers int
+}
+
+// NewRunner sets up a Runner with the scraper settings from the store's
+// config and the rules from its rules.toml.
+func NewRunner(db *store.DB) (*Runner, error) {
+	opts := db.Config.Scrape
+	client, err := scrape.NewClient(opts.Timeout, opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	client.UserAgent, client.MaxBodySize = opts.UserAgent, opts.MaxBodySize
+	r, err := rules.Load(db.StoreLoc)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{DB: db, Client: client, Rules: r, Workers: DefaultWorkers}, nil
+}
+
+// Source is the url a bookmark's metadata is fetched from.
+func Source(bm store.Bookmark) string {
+	if bm.OriginalUrl != "" {
+		return bm.OriginalUrl
+	}
+	return bm.Url
+}
+
+// Job fetches the metadata for a queued bookmark and saves it. If the page
+// can't be fetched the failure is recorded so the job is retried later with
+// backoff. Enricher warnings are returned alongside the updated bookmark.
+func (r *Runner) Job(ctx context.Context, job store.EnrichmentJob) (store.Bookmark, []string, error) {
+	bm, err := store.GetBookmark(r.DB, job.BookmarkId)
+	if errors.Is(err, store.ErrNotFound) {
+		// deleted before it could be enriched, possibly on another host
+		return bm, nil, store.CompleteEnrichment(r.DB, job.BookmarkId)
+	}
+	if err != nil {
+		return bm, nil, err
+	}
+
+	if !scrape.Supports(Source(bm)) {
+		// there is nothing to fetch for links like mailto: and file:
+		return bm, nil, store.CompleteEnrichment(r.DB, job.BookmarkId)
+	}
+
+	result := r.finish(r.fetch(ctx, job, Source(bm)))
+	return result.Bookmark, result.Warnings, result.Err
+}
+
+// fetched is a page fetched for a job, along with its images.
+type fetched struct {
+	job      store.EnrichmentJob
+	md       *scrape.Metadata
+	err      error
+	images   map[string]*scrape.Image
+	warnings []string
+}
+
+// fetch downloads everything for a job without touching the database, so it
+// can run alongside other fetches.
+func (r *Runner) fetch(ctx context.Context, job store.EnrichmentJob, source string) fetched {
+	f := fetched{job: job}
+	f.md, f.err = r.Client.Fetch(ctx, source)
+	if f.err == nil {
+		f.images, f.warnings = r.fetchImages(ctx, f.md)
+	}
+	return f
+}
+
+// finish saves what fetch downloaded.
+func (r *Runner) finish(f fetched) Result {
+	bm, warnings, err := r.Save(f.job, f.md, f.err)
+	if err == nil {
+		warnings = append(warnings, f.warnings...)
+		warnings = append(warnings, r.saveImages(bm.Id, f.images)...)
+	}
+	return Result{Job: f.job, Bookmark: bm, Warnings: warnings, Err: err}
+}
+
+// Save records the outcome of fetching a job's page: the metadata is applied
+// to the bookmark, or if fetchErr is set the failure is recorded so the job
+// is retried later.
+func (r *Runner) Save(job store.EnrichmentJob, md *scrape.Metadata, fetchErr error) (store.Bookmark, []string, error) {
+	bm, err := store.GetBookmark(r.DB, job.BookmarkId)
+	if errors.Is(err, store.ErrNotFound) {
+		return bm, nil, store.CompleteEnrichment(r.DB, job.BookmarkId)
+	}
+	if err != nil {
+		return bm, nil, err
+	}
+
+	if fetchErr != nil {
+		if _, failErr := store.FailEnrichment(r.DB, job, fetchErr); failErr != nil {
+			return bm, nil, errors.Join(fetchErr, failErr)
+		}
+		return bm, nil, fetchErr
+	}
+
+	enriched := Apply(bm, md, job.Primary)
+	if r.Rules != nil {
+		enriched = r.Rules.Apply(enriched)
+	}
+	err = store.UpdateBookmark(r.DB, bm, enriched)
+	var dup *store.DuplicateError
+	if errors.As(err, &dup) {
+		// the page turned out to be bookmarked already under another url,
+		// keep ours and leave merging them to mark dedupe
+		md.Warnings = append(md.Warnings, fmt.Sprintf("%s is already bookmarked as %d, run mark dedupe to merge them", enriched.Url, dup.Existing.Id))
+		enriched.Url = bm.Url
+		err = store.UpdateBookmark(r.DB, bm, enriched)
+	}
+	if err != nil {
+		return bm, md.Warnings, err
+	}
+
+	return enriched, md.Warnings, store.CompleteEnrichment(r.DB, job.BookmarkId)
+}
+
+// Result is reported for every job processed by Run.
+type Result struct {
+	Job      store.EnrichmentJob
+	Bookmark store.Bookmark
+	Warnings []string
+	Err      error
+}
+
+// Pending works through every job that is due, or every job including the
+// failed ones when includeFailed is set. report is called after each job.
+func (r *Runner) Pending(ctx context.Context, includeFailed bool, report func(Result)) error {
+	jobs, err := store.PendingEnrichments(r.DB, time.Now(), includeFailed)
+	if err != nil {
+		return errors.Join(errors.New("unable to list pending enrichments"), err)
+	}
+	return r.Run(ctx, jobs, report)
+}
+
+// Run works through the jobs, fetching up to Workers pages at once. The
+// database is only used from the calling goroutine, which is also where
+// report is called after each job.
+func (r *Runner) Run(ctx context.Context, jobs []store.EnrichmentJob, report func(Result)) error {
+	type item struct {
+		job    store.EnrichmentJob
+		source string
+	}
+	if report == nil {
+		report = func(Result) {}
+	}
+
+	items := []item{}
+	for _, job := range jobs {
+		bm, err := store.GetBookmark(r.DB, job.BookmarkId)
+		if errors.Is(err, store.ErrNotFound) {
+			// deleted before it could be enriched, possibly on another host
+			err = store.CompleteEnrichment(r.DB, job.BookmarkId)
+		} else if err == nil && !scrape.Supports(Source(bm)) {
+			err = store.CompleteEnrichment(r.DB, job.BookmarkId)
+		} else if err == nil {
+			items = append(items, item{job: job, source: Source(bm)})
+			continue
+		}
+		if err != nil {
+			report(Result{Job: job, Bookmark: bm, Err: err})
+		}
+	}
+
+	workers := r.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan item)
+	results := make(chan fetched)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range queue {
+				results <- r.fetch(ctx, it.job, it.source)
+			}
+		}()
+	}
+	go func() {
+		defer close(results)
+		defer wg.Wait()
+		defer close(queue)
+		for _, it := range items {
+			select {
+			case queue <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for f := range results {
+		if ctx.Err() != nil {
+			// interrupted, the job stays queued without counting as a failure
+			continue
+		}
+		report(r.finish(f))
+	}
+	return ctx.Err()
+}
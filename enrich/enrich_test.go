@@ -0,0 +1,69 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lukasmwerner/mark/scrape"
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestApplyEnrichedFields(t *testing.T) {
+	bm := store.Bookmark{Url: "https://news.ycombinator.com/item?id=8863"}
+	md := &scrape.Metadata{
+		URL:       "https://news.ycombinator.com/item?id=8863",
+		Title:     "My YC app: Dropbox",
+		LinkedURL: "http://www.getdropbox.com/u/2/screencast.html",
+		Authors:   []string{"someone"},
+		Stars:     12,
+		Duration:  90 * time.Second,
+	}
+
+	applied := Apply(bm, md, "resolved")
+	if applied.Url != "https://news.ycombinator.com/item?id=8863" {
+		t.Errorf("saved under %s", applied.Url)
+	}
+	if applied.LinkedUrl != md.LinkedURL || applied.Stars != 12 || applied.Duration != 90*time.Second {
+		t.Errorf("got linked url %q, stars %d and duration %s", applied.LinkedUrl, applied.Stars, applied.Duration)
+	}
+	if !reflect.DeepEqual(applied.Authors, []string{"someone"}) {
+		t.Errorf("got authors %q", applied.Authors)
+	}
+
+	applied = Apply(bm, md, "linked")
+	if applied.Url != md.LinkedURL || applied.OriginalUrl != bm.Url {
+		t.Errorf("saved under %s, original %s", applied.Url, applied.OriginalUrl)
+	}
+
+	// pages that aren't discussions keep their own url
+	applied = Apply(store.Bookmark{Url: "https://example.com/a"}, &scrape.Metadata{URL: "https://example.com/b"}, "linked")
+	if applied.Url != "https://example.com/b" {
+		t.Errorf("saved under %s", applied.Url)
+	}
+}
+
+func TestPrimaryURLCanonical(t *testing.T) {
+	tests := []struct {
+		name      string
+		resolved  string
+		canonical string
+		want      string
+	}{
+		{"same page", "https://example.com/post?utm_source=x", "https://example.com/post", "https://example.com/post"},
+		{"www", "https://www.example.com/post", "https://example.com/post", "https://example.com/post"},
+		{"homepage", "https://example.com/post", "https://example.com/", "https://example.com/post"},
+		{"root of the root", "https://example.com/", "https://example.com", "https://example.com"},
+		{"other host", "https://example.com/post", "https://syndicated.example.org/post", "https://example.com/post"},
+		{"subdomain", "https://blog.example.com/post", "https://example.com/post", "https://blog.example.com/post"},
+		{"none", "https://example.com/post", "", "https://example.com/post"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bm := store.Bookmark{Url: test.resolved, OriginalUrl: test.resolved, ResolvedUrl: test.resolved, CanonicalLink: test.canonical}
+			if got := PrimaryURL(bm, "canonical"); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lukasmwerner/mark/scrape"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// fetchImages downloads the page's favicon and preview image, as far as the
+// [cache] section of config.toml asks for them. Failures are only warnings,
+// the bookmark is still saved without the image.
+func (r *Runner) fetchImages(ctx context.Context, md *scrape.Metadata) (map[string]*scrape.Image, []string) {
+	wanted := map[string]string{}
+	if r.DB.Config.Cache.Icons && md.Icon != "" {
+		wanted[store.ImageIcon] = md.Icon
+	}
+	if r.DB.Config.Cache.Images && md.Image != "" {
+		wanted[store.ImagePreview] = md.Image
+	}
+
+	images := map[string]*scrape.Image{}
+	warnings := []string{}
+	for kind, url := range wanted {
+		image, err := r.Client.FetchImage(ctx, url)
+		var status *scrape.StatusError
+		if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+			// most sites don't have a /favicon.ico, that's fine
+			continue
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("unable to fetch %s: %s", kind, err))
+			continue
+		}
+		images[kind] = image
+	}
+	return images, warnings
+}
+
+// saveImages puts the downloaded images in the cache.
+func (r *Runner) saveImages(id store.BookmarkId, images map[string]*scrape.Image) []string {
+	warnings := []string{}
+	for kind, image := range images {
+		_, err := store.SaveImage(r.DB, id, kind, image.URL, image.ContentType, image.Data)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("unable to cache %s: %s", kind, err))
+		}
+	}
+	return warnings
+}
+
+// CacheImages downloads and caches the images of a bookmark that was saved
+// with metadata fetched outside of a job, returning any warnings.
+func (r *Runner) CacheImages(ctx context.Context, id store.BookmarkId, md *scrape.Metadata) []string {
+	images, warnings := r.fetchImages(ctx, md)
+	return append(warnings, r.saveImages(id, images)...)
+}
@@ -0,0 +1,239 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Feed formats.
+const (
+	FeedAtom = "atom"
+	FeedRSS  = "rss"
+)
+
+// FeedOptions describe a feed of bookmarks.
+type FeedOptions struct {
+	Title string
+	// Link is the page feed readers link the feed to, Self is where the feed
+	// itself can be fetched. Both are optional.
+	Link string
+	Self string
+	// Limit is how many of the newest bookmarks the feed has, 0 for all.
+	Limit int
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// atomNamespace is the namespace of Atom feeds, which RSS feeds borrow the
+// self link from.
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	// Atom binds the atom: prefix of the channel's atom:link, which
+	// encoding/xml writes as it is without declaring it.
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          *atomLink `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	GUID        string   `xml:"guid"`
+	Categories  []string `xml:"category"`
+}
+
+// WriteFeed writes the newest bookmarks as an Atom or RSS 2.0 feed. Titles and
+// urls become the entries' titles and links, descriptions their summaries,
+// tags their categories, and the time a bookmark was added when it was
+// published.
+func WriteFeed(w io.Writer, format string, bookmarks []store.Bookmark, opts FeedOptions) error {
+	bookmarks = newest(bookmarks, opts.Limit)
+	var feed any
+	switch format {
+	case FeedAtom:
+		feed = atom(bookmarks, opts)
+	case FeedRSS:
+		feed = rss(bookmarks, opts)
+	default:
+		return errors.New("unknown feed format " + format + ", use atom or rss")
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// newest sorts the bookmarks newest first and keeps limit of them.
+func newest(bookmarks []store.Bookmark, limit int) []store.Bookmark {
+	sorted := append([]store.Bookmark{}, bookmarks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+		}
+		return sorted[i].Id > sorted[j].Id
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+func atom(bookmarks []store.Bookmark, opts FeedOptions) atomFeed {
+	feed := atomFeed{
+		Title:  opts.Title,
+		ID:     feedID(opts),
+		Author: atomAuthor{Name: "mark"},
+	}
+	if opts.Link != "" {
+		feed.Links = append(feed.Links, atomLink{Href: opts.Link, Rel: "alternate"})
+	}
+	if opts.Self != "" {
+		feed.Links = append(feed.Links, atomLink{Href: opts.Self, Rel: "self"})
+	}
+
+	var updated time.Time
+	for _, b := range bookmarks {
+		changed := b.UpdatedAt
+		if changed.IsZero() {
+			changed = b.CreatedAt
+		}
+		if changed.After(updated) {
+			updated = changed
+		}
+		entry := atomEntry{
+			Title:     feedTitle(b),
+			ID:        b.Url,
+			Link:      atomLink{Href: b.Url},
+			Published: formatTime(b.CreatedAt, time.RFC3339),
+			Updated:   formatTime(changed, time.RFC3339),
+			Summary:   b.Description,
+		}
+		if entry.Updated == "" {
+			// atom requires every entry to have been updated at some point
+			entry.Updated = time.Unix(0, 0).UTC().Format(time.RFC3339)
+		}
+		for _, tag := range store.NormalizeTags(b.Tags) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = formatTime(updated, time.RFC3339)
+	return feed
+}
+
+func rss(bookmarks []store.Bookmark, opts FeedOptions) rssFeed {
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    atomNamespace,
+		Channel: rssChannel{
+			Title:       opts.Title,
+			Link:        opts.Link,
+			Description: "Bookmarks saved with mark",
+		},
+	}
+	if feed.Channel.Link == "" {
+		feed.Channel.Link = opts.Self
+	}
+	if opts.Self != "" {
+		feed.Channel.Self = &atomLink{Href: opts.Self, Rel: "self"}
+	}
+
+	for _, b := range bookmarks {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       feedTitle(b),
+			Link:        b.Url,
+			Description: b.Description,
+			PubDate:     formatTime(b.CreatedAt, time.RFC1123Z),
+			GUID:        b.Url,
+			Categories:  store.NormalizeTags(b.Tags),
+		})
+	}
+	if len(bookmarks) > 0 {
+		feed.Channel.LastBuildDate = feed.Channel.Items[0].PubDate
+	}
+	return feed
+}
+
+func feedID(opts FeedOptions) string {
+	switch {
+	case opts.Self != "":
+		return opts.Self
+	case opts.Link != "":
+		return opts.Link
+	default:
+		return "urn:mark:feed:" + slugify(opts.Title)
+	}
+}
+
+func feedTitle(b store.Bookmark) string {
+	if b.Title != "" {
+		return b.Title
+	}
+	return b.Url
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
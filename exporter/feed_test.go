@@ -0,0 +1,148 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+var feedBookmarks = []store.Bookmark{
+	{Id: 1, Url: "https://example.com/old", Title: "Old", CreatedAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+	{Id: 2, Url: "https://example.com/?a=1&b=<2>", Title: `Tom & "Jerry" <3`, Description: "<p>Some html</p> & more", Tags: []string{"cartoons", "tv"},
+		CreatedAt: time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC), UpdatedAt: time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC)},
+	{Id: 3, Url: "https://example.com/undated"},
+}
+
+func writeFeed(t *testing.T, format string, opts FeedOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteFeed(&buf, format, feedBookmarks, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteAtom(t *testing.T) {
+	var feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			Title string `xml:"title"`
+			Link  struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Published  string `xml:"published"`
+			Updated    string `xml:"updated"`
+			Summary    string `xml:"summary"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+		} `xml:"entry"`
+	}
+	data := writeFeed(t, FeedAtom, FeedOptions{Title: "Bookmarks", Link: "https://example.com/", Self: "https://example.com/feed.xml", Limit: 2})
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("%s\n%s", err, data)
+	}
+
+	if feed.ID != "https://example.com/feed.xml" || len(feed.Links) != 2 || feed.Links[1].Rel != "self" {
+		t.Errorf("got id %q and links %+v", feed.ID, feed.Links)
+	}
+	if feed.Updated != "2024-03-03T08:00:00Z" {
+		t.Errorf("got updated %q", feed.Updated)
+	}
+	// the undated bookmark is the oldest and left out by the limit
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries:\n%s", len(feed.Entries), data)
+	}
+
+	entry := feed.Entries[0]
+	if entry.Title != feedBookmarks[1].Title || entry.Link.Href != feedBookmarks[1].Url || entry.Summary != feedBookmarks[1].Description {
+		t.Errorf("got title %q, link %q and summary %q", entry.Title, entry.Link.Href, entry.Summary)
+	}
+	if len(entry.Categories) != 2 || entry.Categories[1].Term != "tv" {
+		t.Errorf("got categories %+v", entry.Categories)
+	}
+	for _, date := range []string{entry.Published, entry.Updated, feed.Entries[1].Updated} {
+		if _, err := time.Parse(time.RFC3339, date); err != nil {
+			t.Errorf("invalid date: %s", err)
+		}
+	}
+	if entry.Published != "2024-03-02T10:30:00Z" || entry.Updated != "2024-03-03T08:00:00Z" {
+		t.Errorf("got published %q and updated %q", entry.Published, entry.Updated)
+	}
+}
+
+func TestWriteRSS(t *testing.T) {
+	var feed struct {
+		XMLName xml.Name `xml:"rss"`
+		Version string   `xml:"version,attr"`
+		Channel struct {
+			// only found when the atom prefix is bound to its namespace,
+			// and before Link, which would take any link element
+			Self struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"http://www.w3.org/2005/Atom link"`
+			Title         string `xml:"title"`
+			Link          string `xml:"link"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string   `xml:"title"`
+				Link        string   `xml:"link"`
+				Description string   `xml:"description"`
+				PubDate     string   `xml:"pubDate"`
+				GUID        string   `xml:"guid"`
+				Categories  []string `xml:"category"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	data := writeFeed(t, FeedRSS, FeedOptions{Title: "Bookmarks", Self: "https://example.com/feed.xml"})
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("%s\n%s", err, data)
+	}
+
+	channel := feed.Channel
+	if feed.Version != "2.0" || channel.Link != "https://example.com/feed.xml" {
+		t.Errorf("got version %q and link %q", feed.Version, channel.Link)
+	}
+	if channel.Self.Href != "https://example.com/feed.xml" || channel.Self.Rel != "self" {
+		t.Errorf("got self link %+v:\n%s", channel.Self, data)
+	}
+	if len(channel.Items) != 3 {
+		t.Fatalf("got %d items:\n%s", len(channel.Items), data)
+	}
+
+	item := channel.Items[0]
+	if item.Title != feedBookmarks[1].Title || item.Link != feedBookmarks[1].Url || item.GUID != feedBookmarks[1].Url {
+		t.Errorf("got title %q, link %q and guid %q", item.Title, item.Link, item.GUID)
+	}
+	if item.Description != feedBookmarks[1].Description || !reflect.DeepEqual(item.Categories, []string{"cartoons", "tv"}) {
+		t.Errorf("got description %q and categories %q", item.Description, item.Categories)
+	}
+	published, err := time.Parse(time.RFC1123Z, item.PubDate)
+	if err != nil || !published.Equal(feedBookmarks[1].CreatedAt) {
+		t.Errorf("got pubDate %q: %v", item.PubDate, err)
+	}
+	if channel.LastBuildDate != item.PubDate {
+		t.Errorf("got lastBuildDate %q", channel.LastBuildDate)
+	}
+	// the undated bookmark has no date rather than a made up one
+	if undated := channel.Items[2]; undated.Title != "https://example.com/undated" || undated.PubDate != "" {
+		t.Errorf("got title %q and pubDate %q", undated.Title, undated.PubDate)
+	}
+}
+
+func TestWriteFeedUnknown(t *testing.T) {
+	if err := WriteFeed(&bytes.Buffer{}, "json", feedBookmarks, FeedOptions{}); err == nil {
+		t.Error("wrote an unknown feed format")
+	}
+}
@@ -0,0 +1,213 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// JSONVersion is the version of the schema WriteJSON writes. It changes when
+// fields are removed or change meaning, fields may be added without a new
+// version and readers ignore the ones they don't know.
+const JSONVersion = 1
+
+// JSONHeader starts every json export. In a json document the bookmarks
+// follow in its bookmarks field, in json lines it is the first line and
+// every following line is a bookmark:
+//
+//	{"format":"mark","version":1,"exported_at":"2024-05-01T10:00:00Z"}
+//	{"id":1,"url":"https://example.com","title":"Example","tags":["a"],...}
+type JSONHeader struct {
+	// Format is always "mark".
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// JSONBookmark is a bookmark as it is written in json exports, with every
+// field mark keeps except the canonical url, which is computed again when the
+// bookmark is imported. Times are RFC 3339 and left out when unknown. The
+// json, yaml and toml modes of mark show and mark list print the same fields.
+type JSONBookmark struct {
+	ID            int64      `json:"id" yaml:"id" toml:"id"`
+	URL           string     `json:"url" yaml:"url" toml:"url"`
+	Title         string     `json:"title" yaml:"title" toml:"title"`
+	Description   string     `json:"description" yaml:"description" toml:"description"`
+	Notes         string     `json:"notes,omitempty" yaml:"notes,omitempty" toml:"notes,omitempty"`
+	Tags          []string   `json:"tags" yaml:"tags" toml:"tags"`
+	Unread        bool       `json:"unread,omitempty" yaml:"unread,omitempty" toml:"unread,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty" toml:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty" yaml:"updated_at,omitempty" toml:"updated_at,omitempty"`
+	OriginalURL   string     `json:"original_url,omitempty" yaml:"original_url,omitempty" toml:"original_url,omitempty"`
+	ResolvedURL   string     `json:"resolved_url,omitempty" yaml:"resolved_url,omitempty" toml:"resolved_url,omitempty"`
+	CanonicalLink string     `json:"canonical_link,omitempty" yaml:"canonical_link,omitempty" toml:"canonical_link,omitempty"`
+	Redirects     []string   `json:"redirects,omitempty" yaml:"redirects,omitempty" toml:"redirects,omitempty"`
+	UserFields    []string   `json:"user_fields,omitempty" yaml:"user_fields,omitempty" toml:"user_fields,omitempty"`
+	Keywords      []string   `json:"keywords,omitempty" yaml:"keywords,omitempty" toml:"keywords,omitempty"`
+	LinkedURL     string     `json:"linked_url,omitempty" yaml:"linked_url,omitempty" toml:"linked_url,omitempty"`
+	Authors       []string   `json:"authors,omitempty" yaml:"authors,omitempty" toml:"authors,omitempty"`
+	Stars         int        `json:"stars,omitempty" yaml:"stars,omitempty" toml:"stars,omitempty"`
+	// Duration is in seconds.
+	Duration int64 `json:"duration,omitempty" yaml:"duration,omitempty" toml:"duration,omitempty"`
+}
+
+// JSONOptions control the layout of a json export.
+type JSONOptions struct {
+	// Lines writes json lines, one bookmark per line after the header,
+	// instead of a single json document.
+	Lines bool
+}
+
+// NewJSONHeader returns the header of an export made now.
+func NewJSONHeader() JSONHeader {
+	return JSONHeader{Format: "mark", Version: JSONVersion, ExportedAt: time.Now().UTC().Truncate(time.Second)}
+}
+
+// ToJSON converts a bookmark to its json form.
+func ToJSON(b store.Bookmark) JSONBookmark {
+	return JSONBookmark{
+		ID:            int64(b.Id),
+		URL:           b.Url,
+		Title:         b.Title,
+		Description:   b.Description,
+		Notes:         b.Notes,
+		Tags:          store.NormalizeTags(b.Tags),
+		Unread:        b.Unread,
+		CreatedAt:     jsonTime(b.CreatedAt),
+		UpdatedAt:     jsonTime(b.UpdatedAt),
+		OriginalURL:   b.OriginalUrl,
+		ResolvedURL:   b.ResolvedUrl,
+		CanonicalLink: b.CanonicalLink,
+		Redirects:     b.Redirects,
+		UserFields:    b.UserFields,
+		Keywords:      b.Keywords,
+		LinkedURL:     b.LinkedUrl,
+		Authors:       b.Authors,
+		Stars:         b.Stars,
+		Duration:      int64(b.Duration / time.Second),
+	}
+}
+
+// Bookmark converts the json form back to a bookmark.
+func (j JSONBookmark) Bookmark() store.Bookmark {
+	b := store.Bookmark{
+		Id:            store.BookmarkId(j.ID),
+		Url:           j.URL,
+		Title:         j.Title,
+		Description:   j.Description,
+		Notes:         j.Notes,
+		Tags:          store.NormalizeTags(j.Tags),
+		Unread:        j.Unread,
+		OriginalUrl:   j.OriginalURL,
+		ResolvedUrl:   j.ResolvedURL,
+		CanonicalLink: j.CanonicalLink,
+		Redirects:     j.Redirects,
+		UserFields:    j.UserFields,
+		Keywords:      j.Keywords,
+		LinkedUrl:     j.LinkedURL,
+		Authors:       j.Authors,
+		Stars:         j.Stars,
+		Duration:      time.Duration(j.Duration) * time.Second,
+	}
+	if j.CreatedAt != nil {
+		b.CreatedAt = *j.CreatedAt
+	}
+	if j.UpdatedAt != nil {
+		b.UpdatedAt = *j.UpdatedAt
+	}
+	return b
+}
+
+func jsonTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// WriteJSON writes the bookmarks in mark's own json format, which keeps every
+// field so mark import json can restore them.
+func WriteJSON(w io.Writer, bookmarks []store.Bookmark, opts JSONOptions) error {
+	jw, err := NewJSONWriter(w, opts)
+	if err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		if err := jw.Write(b); err != nil {
+			return err
+		}
+	}
+	return jw.Close()
+}
+
+// JSONWriter writes a json export one bookmark at a time, so exporting a
+// large store doesn't need all of it in memory.
+type JSONWriter struct {
+	out   *bufio.Writer
+	lines bool
+	count int
+}
+
+// NewJSONWriter starts a json export by writing its header.
+func NewJSONWriter(w io.Writer, opts JSONOptions) (*JSONWriter, error) {
+	jw := &JSONWriter{out: bufio.NewWriter(w), lines: opts.Lines}
+	header, err := marshalJSON(NewJSONHeader())
+	if err != nil {
+		return nil, err
+	}
+	if jw.lines {
+		jw.out.Write(header)
+		jw.out.WriteString("\n")
+		return jw, nil
+	}
+	// the document is the header with a bookmarks field, written by hand to
+	// keep one bookmark per line
+	jw.out.Write(header[:len(header)-1])
+	jw.out.WriteString(`,"bookmarks":[`)
+	return jw, nil
+}
+
+// Write adds a bookmark to the export.
+func (jw *JSONWriter) Write(b store.Bookmark) error {
+	line, err := marshalJSON(ToJSON(b))
+	if err != nil {
+		return err
+	}
+	switch {
+	case jw.lines:
+		line = append(line, '\n')
+	case jw.count > 0:
+		jw.out.WriteString(",\n")
+	default:
+		jw.out.WriteString("\n")
+	}
+	jw.count++
+	_, err = jw.out.Write(line)
+	return err
+}
+
+// Close ends the export and flushes it, it doesn't close the writer the
+// export went to.
+func (jw *JSONWriter) Close() error {
+	if !jw.lines {
+		jw.out.WriteString("\n]}\n")
+	}
+	return jw.out.Flush()
+}
+
+// marshalJSON encodes v on a single line without escaping html, like the
+// encoder used for json lines.
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,95 @@
+// Package exporter writes bookmarks out in formats that browsers and other
+// tools can read.
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// NetscapeOptions control the layout of an exported bookmark file.
+type NetscapeOptions struct {
+	// FolderPerTag puts every bookmark in a folder for each of its tags,
+	// untagged bookmarks stay at the top. Otherwise all bookmarks are at the
+	// top and the tags are only kept in the TAGS attribute.
+	FolderPerTag bool
+}
+
+const netscapeHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+`
+
+// WriteNetscape writes the bookmarks in the bookmarks.html format browsers
+// import. mark import html reads back the url, title, description, tags and
+// the times to the second, with the whitespace around the title and
+// description trimmed. Notes, unread and the urls, authors and other
+// metadata found when enriching aren't in the format and are lost, use the
+// json export to keep them.
+func WriteNetscape(w io.Writer, bookmarks []store.Bookmark, opts NetscapeOptions) error {
+	out := bufio.NewWriter(w)
+	out.WriteString(netscapeHeader)
+	out.WriteString("<DL><p>\n")
+
+	if opts.FolderPerTag {
+		byTag := map[string][]store.Bookmark{}
+		for _, bm := range bookmarks {
+			for _, tag := range bm.Tags {
+				byTag[tag] = append(byTag[tag], bm)
+			}
+		}
+		tags := []string{}
+		for tag := range byTag {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		now := time.Now().Unix()
+		for _, tag := range tags {
+			fmt.Fprintf(out, "    <DT><H3 ADD_DATE=\"%d\" LAST_MODIFIED=\"%d\">%s</H3>\n", now, now, html.EscapeString(tag))
+			out.WriteString("    <DL><p>\n")
+			for _, bm := range byTag[tag] {
+				writeNetscapeBookmark(out, bm, "        ")
+			}
+			out.WriteString("    </DL><p>\n")
+		}
+	}
+
+	for _, bm := range bookmarks {
+		if opts.FolderPerTag && len(bm.Tags) > 0 {
+			continue
+		}
+		writeNetscapeBookmark(out, bm, "    ")
+	}
+
+	out.WriteString("</DL><p>\n")
+	return out.Flush()
+}
+
+func writeNetscapeBookmark(out *bufio.Writer, bm store.Bookmark, indent string) {
+	fmt.Fprintf(out, "%s<DT><A HREF=\"%s\"", indent, html.EscapeString(bm.Url))
+	if !bm.CreatedAt.IsZero() {
+		fmt.Fprintf(out, " ADD_DATE=\"%d\"", bm.CreatedAt.Unix())
+	}
+	if !bm.UpdatedAt.IsZero() {
+		fmt.Fprintf(out, " LAST_MODIFIED=\"%d\"", bm.UpdatedAt.Unix())
+	}
+	if len(bm.Tags) > 0 {
+		fmt.Fprintf(out, " TAGS=\"%s\"", html.EscapeString(strings.Join(bm.Tags, ",")))
+	}
+	fmt.Fprintf(out, ">%s</A>\n", html.EscapeString(bm.Title))
+	if bm.Description != "" {
+		fmt.Fprintf(out, "%s<DD>%s\n", indent, html.EscapeString(bm.Description))
+	}
+}
@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// siteAssets are copied into the site as they are.
+var siteAssets = []string{"site/style.css", "site/search.js"}
+
+// siteManifest lists the files the last export wrote into the directory, so
+// the next one only removes pages it wrote itself.
+const siteManifest = ".mark-site.json"
+
+// SitePage is what the site templates are executed with.
+type SitePage struct {
+	// Title is the title of the site, Heading the one of the page, which is
+	// empty for the index.
+	Title   string
+	Heading string
+	// Root leads from the page back to the top of the site.
+	Root      string
+	Bookmarks []store.Bookmark
+	Groups    []Group
+	Tags      []SiteTag
+	// Index is the json the search on the index page runs on.
+	Index     template.JS
+	Generated time.Time
+}
+
+// SiteTag is a tag with a page of its own.
+type SiteTag struct {
+	Name  string
+	Count int
+}
+
+type searchEntry struct {
+	Title       string   `json:"title"`
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// WriteSite renders a static html site into dir: an index.html with every
+// bookmark grouped like opts.GroupBy and a search over them, a page for each
+// tag in tags/, and the style.css and search.js they use. Everything the
+// search needs is in index.html, so the site works from any web host or
+// straight from disk. Pages an earlier export wrote that aren't needed
+// anymore are removed, other files in dir are left alone.
+func WriteSite(dir string, bookmarks []store.Bookmark, opts TemplateOptions) error {
+	groups, err := GroupBookmarks(bookmarks, opts.GroupBy)
+	if err != nil {
+		return err
+	}
+	byTag, err := GroupBookmarks(bookmarks, GroupByTag)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "tags"), 0755); err != nil {
+		return err
+	}
+
+	pages := map[string]string{}
+	slugs := map[string]bool{}
+	tags := []SiteTag{}
+	for _, g := range byTag {
+		if g.Name == "" {
+			continue
+		}
+		slug := slugify(g.Name)
+		for i := 2; slugs[slug]; i++ {
+			slug = fmt.Sprintf("%s-%d", slugify(g.Name), i)
+		}
+		slugs[slug] = true
+		pages[g.Name] = "tags/" + slug + ".html"
+		tags = append(tags, SiteTag{Name: g.Name, Count: len(g.Bookmarks)})
+	}
+
+	entries := []searchEntry{}
+	for _, b := range bookmarks {
+		entries = append(entries, searchEntry{Title: b.Title, URL: b.Url, Description: b.Description, Tags: store.NormalizeTags(b.Tags)})
+	}
+	index, err := json.Marshal(map[string]any{"bookmarks": entries, "pages": pages})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	written := map[string]bool{}
+	render := func(name string, target string, page SitePage) error {
+		funcs := template.FuncMap(TemplateFuncs())
+		funcs["tagpage"] = func(tag string) string { return page.Root + pages[tag] }
+		t, err := template.New(name).Funcs(funcs).ParseFS(opts.Templates, "site/partials.html", "site/"+name)
+		if err != nil {
+			return errors.Join(errors.New("unable to parse the template site/"+name), err)
+		}
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, name, page); err != nil {
+			return err
+		}
+		written[target] = true
+		return os.WriteFile(filepath.Join(dir, filepath.FromSlash(target)), buf.Bytes(), 0644)
+	}
+
+	err = render("index.html", "index.html", SitePage{
+		Title:     opts.Title,
+		Bookmarks: bookmarks,
+		Groups:    groups,
+		Tags:      tags,
+		Index:     template.JS(index),
+		Generated: now,
+	})
+	if err != nil {
+		return err
+	}
+	for _, g := range byTag {
+		if g.Name == "" {
+			continue
+		}
+		err := render("tag.html", pages[g.Name], SitePage{
+			Title:     opts.Title,
+			Heading:   g.Name,
+			Root:      "../",
+			Bookmarks: g.Bookmarks,
+			Generated: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, asset := range siteAssets {
+		data, err := fs.ReadFile(opts.Templates, asset)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, path.Base(asset)), data, 0644); err != nil {
+			return err
+		}
+		written[path.Base(asset)] = true
+	}
+
+	// remove the pages of tags that no bookmark has anymore
+	old, err := readSiteManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range old {
+		if written[file] || !strings.HasPrefix(file, "tags/") || path.Clean(file) != file {
+			continue
+		}
+		err := os.Remove(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return writeSiteManifest(dir, written)
+}
+
+func readSiteManifest(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, siteManifest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Files []string `json:"files"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Join(errors.New("unable to read "+siteManifest), err)
+	}
+	return manifest.Files, nil
+}
+
+func writeSiteManifest(dir string, written map[string]bool) error {
+	files := []string{}
+	for file := range written {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	data, err := json.MarshalIndent(map[string]any{"files": files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, siteManifest), append(data, '\n'), 0644)
+}
+
+// slugify turns a tag into a file name.
+func slugify(tag string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "tag"
+	}
+	return slug
+}
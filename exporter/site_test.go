@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestWriteSiteRemovesOnlyItsOwnPages(t *testing.T) {
+	dir := t.TempDir()
+	opts := TemplateOptions{Title: "Links", GroupBy: GroupByTag, Templates: Templates("")}
+	// a page someone put there by hand
+	if err := os.MkdirAll(filepath.Join(dir, "tags"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tags", "about.html"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WriteSite(dir, []store.Bookmark{
+		{Url: "https://go.dev", Title: "Go", Tags: []string{"go"}},
+		{Url: "https://sqlite.org", Title: "SQLite", Tags: []string{"sqlite"}},
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range []string{"index.html", "tags/go.html", "tags/sqlite.html", "tags/about.html", siteManifest} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(page))); err != nil {
+			t.Errorf("%s is missing: %s", page, err)
+		}
+	}
+
+	// sqlite isn't a tag anymore
+	err = WriteSite(dir, []store.Bookmark{{Url: "https://go.dev", Title: "Go", Tags: []string{"go"}}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tags", "sqlite.html")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the page of the old tag is still there: %v", err)
+	}
+	for _, page := range []string{"tags/go.html", "tags/about.html"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(page))); err != nil {
+			t.Errorf("%s is missing: %s", page, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package exporter
+
+import (
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// defaultTemplates are used for every template the user hasn't replaced.
+//
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Templates returns the export templates, where files in dir replace the
+// embedded default with the same name, like dir/markdown.tmpl or
+// dir/site/index.html.
+func Templates(dir string) fs.FS {
+	defaults, _ := fs.Sub(defaultTemplates, "templates")
+	if dir == "" {
+		return defaults
+	}
+	return overlayFS{top: os.DirFS(dir), bottom: defaults}
+}
+
+type overlayFS struct {
+	top    fs.FS
+	bottom fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.top.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	return o.bottom.Open(name)
+}
+
+// WriteDefaultTemplates copies the embedded templates into dir so they can be
+// changed, leaving any that are already there alone. It returns the files it
+// wrote.
+func WriteDefaultTemplates(dir string) ([]string, error) {
+	written := []string{}
+	err := fs.WalkDir(defaultTemplates, "templates", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, "templates/")))
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		data, err := defaultTemplates.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		written = append(written, target)
+		return nil
+	})
+	return written, err
+}
+
+// Grouping names how templated exports group bookmarks.
+const (
+	GroupByTag    = "tag"
+	GroupByDomain = "domain"
+	GroupByNone   = "none"
+)
+
+// Group is a heading of a templated export with the bookmarks under it.
+type Group struct {
+	Name      string
+	Bookmarks []store.Bookmark
+}
+
+// GroupBookmarks groups the bookmarks by tag, domain or not at all. With tags
+// a bookmark is in the group of each of its tags, and untagged bookmarks come
+// last in a group without a name. Bookmarks keep their order within a group.
+func GroupBookmarks(bookmarks []store.Bookmark, by string) ([]Group, error) {
+	keys := func(b store.Bookmark) []string { return []string{""} }
+	switch by {
+	case GroupByTag:
+		keys = func(b store.Bookmark) []string {
+			if tags := store.NormalizeTags(b.Tags); len(tags) > 0 {
+				return tags
+			}
+			return []string{""}
+		}
+	case GroupByDomain:
+		keys = func(b store.Bookmark) []string { return []string{domain(b.Url)} }
+	case GroupByNone, "":
+	default:
+		return nil, errors.New("unknown grouping " + by + ", use tag, domain or none")
+	}
+
+	groups := map[string]*Group{}
+	for _, b := range bookmarks {
+		for _, key := range keys(b) {
+			if groups[key] == nil {
+				groups[key] = &Group{Name: key}
+			}
+			groups[key].Bookmarks = append(groups[key].Bookmarks, b)
+		}
+	}
+	sorted := []Group{}
+	for _, g := range groups {
+		sorted = append(sorted, *g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		// the group without a name comes last
+		if (sorted[i].Name == "") != (sorted[j].Name == "") {
+			return sorted[j].Name == ""
+		}
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+	return sorted, nil
+}
+
+// TemplateOptions control templated exports.
+type TemplateOptions struct {
+	// Title heads the export.
+	Title string
+	// GroupBy is GroupByTag, GroupByDomain or GroupByNone.
+	GroupBy string
+	// Templates are the templates to use, see Templates.
+	Templates fs.FS
+}
+
+// TemplateData is what the markdown and org templates are executed with.
+type TemplateData struct {
+	Title     string
+	Groups    []Group
+	Generated time.Time
+}
+
+// WriteMarkdown writes the bookmarks as a markdown list using the template
+// markdown.tmpl.
+func WriteMarkdown(w io.Writer, bookmarks []store.Bookmark, opts TemplateOptions) error {
+	return writeText(w, "markdown.tmpl", bookmarks, opts)
+}
+
+// WriteOrg writes the bookmarks as an org-mode outline using the template
+// org.tmpl.
+func WriteOrg(w io.Writer, bookmarks []store.Bookmark, opts TemplateOptions) error {
+	return writeText(w, "org.tmpl", bookmarks, opts)
+}
+
+func writeText(w io.Writer, name string, bookmarks []store.Bookmark, opts TemplateOptions) error {
+	groups, err := GroupBookmarks(bookmarks, opts.GroupBy)
+	if err != nil {
+		return err
+	}
+	t, err := template.New(name).Funcs(templateFuncs).ParseFS(opts.Templates, name)
+	if err != nil {
+		return errors.Join(errors.New("unable to parse the template "+name), err)
+	}
+	return t.Execute(w, TemplateData{Title: opts.Title, Groups: groups, Generated: time.Now()})
+}
+
+// TemplateFuncs returns the functions every export template has, for the
+// other templates mark runs to have the same ones.
+func TemplateFuncs() map[string]any {
+	funcs := map[string]any{}
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// templateFuncs are available in every export template.
+var templateFuncs = map[string]any{
+	"domain": domain,
+	"join":   strings.Join,
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	},
+	// oneline collapses whitespace, for descriptions in lists
+	"oneline": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+	// md escapes the text of a markdown link
+	"md": strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`).Replace,
+	// org keeps the text of an org link from closing it early
+	"org": strings.NewReplacer(`[`, `(`, `]`, `)`).Replace,
+	// mdurl and orgurl percent-encode what would end the url of a link
+	"mdurl":  strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace,
+	"orgurl": strings.NewReplacer(" ", "%20", "[", "%5B", "]", "%5D").Replace,
+}
+
+// domain is the host of a url without www.
+func domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
@@ -0,0 +1,56 @@
+// Filters the bookmarks embedded in the page as the search box is typed in,
+// so the site works without a server.
+(function () {
+  var index = JSON.parse(document.getElementById("search-index").textContent);
+  var search = document.getElementById("search");
+  var results = document.getElementById("results");
+  var groups = document.getElementById("groups");
+
+  function link(href, text) {
+    var a = document.createElement("a");
+    // leave out links the pages wouldn't have made, like javascript:
+    if (/^(https?|mailto|file|ssh|obsidian):|^tags\//i.test(href)) {
+      a.href = href;
+    }
+    a.textContent = text;
+    return a;
+  }
+
+  function render(b) {
+    var li = document.createElement("li");
+    li.className = "bookmark";
+    li.appendChild(link(b.url, b.title || b.url));
+    if (b.description) {
+      var p = document.createElement("p");
+      p.textContent = b.description;
+      li.appendChild(p);
+    }
+    if (b.tags.length) {
+      var tags = document.createElement("ul");
+      tags.className = "tags";
+      b.tags.forEach(function (tag) {
+        var item = document.createElement("li");
+        item.appendChild(link(index.pages[tag], tag));
+        tags.appendChild(item);
+      });
+      li.appendChild(tags);
+    }
+    return li;
+  }
+
+  search.addEventListener("input", function () {
+    var terms = search.value.toLowerCase().split(/\s+/).filter(Boolean);
+    results.textContent = "";
+    results.hidden = terms.length === 0;
+    groups.hidden = terms.length > 0;
+    index.bookmarks.forEach(function (b) {
+      var text = [b.title, b.url, b.description].concat(b.tags).join(" ").toLowerCase();
+      var matches = terms.length > 0 && terms.every(function (term) {
+        return text.indexOf(term) !== -1;
+      });
+      if (matches) {
+        results.appendChild(render(b));
+      }
+    });
+  });
+})();
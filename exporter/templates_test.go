@@ -0,0 +1,35 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestWriteMarkdownEscapesURLs(t *testing.T) {
+	bookmarks := []store.Bookmark{{Url: "https://en.wikipedia.org/wiki/Go_(game)", Title: "Go [game]"}}
+	var buf bytes.Buffer
+	err := WriteMarkdown(&buf, bookmarks, TemplateOptions{Title: "Links", GroupBy: GroupByNone, Templates: Templates("")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- [Go \[game\]](https://en.wikipedia.org/wiki/Go_%28game%29)`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("got\n%s\nwant a line %s", buf.String(), want)
+	}
+}
+
+func TestWriteOrgEscapesURLs(t *testing.T) {
+	bookmarks := []store.Bookmark{{Url: "https://example.com/a]b", Title: "A [b]"}}
+	var buf bytes.Buffer
+	err := WriteOrg(&buf, bookmarks, TemplateOptions{Title: "Links", GroupBy: GroupByNone, Templates: Templates("")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- [[https://example.com/a%5Db][A (b)]]`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("got\n%s\nwant a line %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,57 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/lukasmwerner/mark/store"
+	"golang.org/x/net/html/charset"
+)
+
+type opmlDoc struct {
+	XMLName  xml.Name      `xml:"opml"`
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// ParseOPML reads the feeds of an OPML subscription list, which every feed
+// reader can export. With folderTags the outlines the feeds are grouped in
+// become their tags.
+func ParseOPML(r io.Reader, folderTags bool) ([]store.Feed, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	var doc opmlDoc
+	if err := dec.Decode(&doc); err != nil {
+		return nil, errors.Join(errors.New("not an opml file"), err)
+	}
+
+	feeds := []store.Feed{}
+	var walk func(outlines []opmlOutline, folders []string)
+	walk = func(outlines []opmlOutline, folders []string) {
+		for _, outline := range outlines {
+			name := firstOf(outline.Title, outline.Text)
+			if url := strings.TrimSpace(outline.XMLURL); url != "" {
+				feed := store.Feed{Url: url, Title: name, Tags: []string{}}
+				if folderTags {
+					feed.Tags = store.NormalizeTags(folders)
+				}
+				feeds = append(feeds, feed)
+			}
+			if len(outline.Outlines) > 0 {
+				walk(outline.Outlines, append(append([]string{}, folders...), name))
+			}
+		}
+	}
+	walk(doc.Outlines, nil)
+	return feeds, nil
+}
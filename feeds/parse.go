@@ -0,0 +1,220 @@
+// Package feeds reads RSS and Atom feeds and OPML subscription lists, and
+// polls subscribed feeds to bookmark their new items.
+package feeds
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+// Parsed is a feed as it was fetched.
+type Parsed struct {
+	Title string
+	Link  string
+	Items []Item
+}
+
+// Item is an entry of a feed.
+type Item struct {
+	// ID identifies the item across polls: its guid or id, or its link when
+	// the feed has neither.
+	ID        string
+	URL       string
+	Title     string
+	Summary   string
+	Published time.Time
+}
+
+type rssDoc struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Link  string    `xml:"link"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 puts the items next to the channel
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+}
+
+type atomDoc struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom feed. Summaries are turned from
+// html into plain text.
+func Parse(r io.Reader) (*Parsed, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return nil, errors.New("not a feed")
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(start.Name.Local) {
+		case "rss", "rdf":
+			var doc rssDoc
+			if err := dec.DecodeElement(&doc, &start); err != nil {
+				return nil, err
+			}
+			return parseRSS(doc), nil
+		case "feed":
+			var doc atomDoc
+			if err := dec.DecodeElement(&doc, &start); err != nil {
+				return nil, err
+			}
+			return parseAtom(doc), nil
+		default:
+			return nil, errors.New("not a feed, the document is <" + start.Name.Local + ">")
+		}
+	}
+}
+
+func parseRSS(doc rssDoc) *Parsed {
+	feed := &Parsed{Title: strings.TrimSpace(doc.Channel.Title), Link: strings.TrimSpace(doc.Channel.Link)}
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		parsed := Item{
+			ID:        firstOf(item.GUID, item.About, item.Link),
+			URL:       strings.TrimSpace(item.Link),
+			Title:     strings.TrimSpace(item.Title),
+			Summary:   plainText(item.Description),
+			Published: parseDate(firstOf(item.PubDate, item.Date)),
+		}
+		if parsed.URL == "" && strings.HasPrefix(parsed.ID, "http") {
+			// a guid that is a permalink
+			parsed.URL = parsed.ID
+		}
+		feed.Items = append(feed.Items, parsed)
+	}
+	return feed
+}
+
+func parseAtom(doc atomDoc) *Parsed {
+	feed := &Parsed{Title: strings.TrimSpace(doc.Title), Link: atomAlternate(doc.Links)}
+	for _, entry := range doc.Entries {
+		link := atomAlternate(entry.Links)
+		feed.Items = append(feed.Items, Item{
+			ID:        firstOf(entry.ID, link),
+			URL:       link,
+			Title:     strings.TrimSpace(entry.Title),
+			Summary:   plainText(firstOf(entry.Summary, entry.Content)),
+			Published: parseDate(firstOf(entry.Published, entry.Updated)),
+		})
+	}
+	return feed
+}
+
+// resolveLinks makes relative links absolute. The links of the items are
+// relative to the feed's own link, which is relative to where the feed was
+// fetched from.
+func (p *Parsed) resolveLinks(feedURL *url.URL) {
+	base := feedURL
+	if link, err := url.Parse(p.Link); err == nil && p.Link != "" {
+		base = feedURL.ResolveReference(link)
+		p.Link = base.String()
+	}
+	for i, item := range p.Items {
+		if item.URL == "" {
+			continue
+		}
+		if link, err := url.Parse(item.URL); err == nil {
+			p.Items[i].URL = base.ResolveReference(link).String()
+		}
+	}
+}
+
+// atomAlternate finds the link to the page itself, which is the one without
+// a rel or with rel="alternate".
+func atomAlternate(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// plainText strips the html most feeds put in their summaries.
+func plainText(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "<&") {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(s)); err == nil {
+			s = doc.Text()
+		}
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate reads the many date formats feeds use, returning the zero time
+// for ones it doesn't know.
+func parseDate(value string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,88 @@
+package feeds
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseFixture(t *testing.T, name string) *Parsed {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	parsed, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestParseRSS(t *testing.T) {
+	parsed := parseFixture(t, "rss.xml")
+	if parsed.Title != "Example News" || parsed.Link != "https://news.example.com/" || len(parsed.Items) != 2 {
+		t.Fatalf("got %+v", parsed)
+	}
+	item := parsed.Items[0]
+	if item.ID != "news-2" || item.URL != "https://news.example.com/go-1-22" || item.Summary != "Range over integers." {
+		t.Errorf("got %+v", item)
+	}
+	if !item.Published.Equal(time.Date(2024, 2, 6, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("got published %s", item.Published)
+	}
+	// the guid is the link when there is no other
+	if item := parsed.Items[1]; item.URL != "https://news.example.com/permalink" || item.Published.IsZero() {
+		t.Errorf("got %+v", item)
+	}
+}
+
+func TestParseRDF(t *testing.T) {
+	parsed := parseFixture(t, "rdf.xml")
+	if parsed.Title != "Example Papers" || len(parsed.Items) != 1 {
+		t.Fatalf("got %+v", parsed)
+	}
+	item := parsed.Items[0]
+	if item.ID != "https://papers.example.org/1" || item.URL != "https://papers.example.org/1" || item.Title != "A paper" {
+		t.Errorf("got %+v", item)
+	}
+	if !item.Published.Equal(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("got published %s", item.Published)
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	parsed := parseFixture(t, "atom.xml")
+	if parsed.Title != "Example Blog" || parsed.Link != "/blog/" || len(parsed.Items) != 2 {
+		t.Fatalf("got %+v", parsed)
+	}
+	first := parsed.Items[1]
+	if first.ID != "tag:example.com,2024:1" || first.URL != "/blog/posts/1" || first.Summary != "Hello, world" {
+		t.Errorf("got %+v", first)
+	}
+	if second := parsed.Items[0]; second.Summary != "About sqlite" {
+		t.Errorf("got summary %q", second.Summary)
+	}
+
+	feedURL, _ := url.Parse("https://example.com/feeds/blog.xml")
+	parsed.resolveLinks(feedURL)
+	if parsed.Link != "https://example.com/blog/" {
+		t.Errorf("resolved the feed's link to %s", parsed.Link)
+	}
+	if got := parsed.Items[0].URL; got != "https://example.com/blog/posts/2" {
+		t.Errorf("resolved posts/2 to %s", got)
+	}
+	if got := parsed.Items[1].URL; got != "https://example.com/blog/posts/1" {
+		t.Errorf("resolved /blog/posts/1 to %s", got)
+	}
+}
+
+func TestParseNotAFeed(t *testing.T) {
+	if _, err := Parse(strings.NewReader("<html><body>Hi</body></html>")); err == nil {
+		t.Error("parsed an html page")
+	}
+}
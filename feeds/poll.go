@@ -0,0 +1,237 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/lukasmwerner/mark/rules"
+	"github.com/lukasmwerner/mark/scrape"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Poller fetches subscribed feeds and bookmarks their new items.
+type Poller struct {
+	DB          *store.DB
+	HTTP        *http.Client
+	UserAgent   string
+	MaxBodySize int64
+	// Rules tag the new bookmarks like mark add does, they may be nil.
+	Rules *rules.Rules
+	// The items already in a feed when it is polled for the first time are
+	// only remembered as seen, unless Backfill is set.
+	Backfill bool
+	// Enrich queues the new bookmarks for mark enrich.
+	Enrich bool
+	// DryRun only counts what would be bookmarked.
+	DryRun bool
+}
+
+// NewPoller makes a poller using the scrape settings and rules of the store.
+func NewPoller(db *store.DB) (*Poller, error) {
+	opts := db.Config.Scrape
+	client, err := scrape.NewClient(opts.Timeout, opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	client.UserAgent, client.MaxBodySize = opts.UserAgent, opts.MaxBodySize
+	r, err := rules.Load(db.StoreLoc)
+	if err != nil {
+		return nil, err
+	}
+	return &Poller{
+		DB:          db,
+		HTTP:        client.HTTP,
+		UserAgent:   client.UserAgent,
+		MaxBodySize: client.MaxBodySize,
+		Rules:       r,
+	}, nil
+}
+
+// Result is what polling a feed did.
+type Result struct {
+	Feed store.Feed
+	// NotModified is set when the feed hasn't changed since the last poll.
+	NotModified bool
+	// Items counts the items in the feed, New the ones with a usable link
+	// not seen before.
+	Items int
+	New   int
+	// Added are the new bookmarks. New items may also be left out by the
+	// feed's filter or be bookmarked already. Invalid counts the items whose
+	// link isn't a usable url, which are tried again on every poll.
+	Added      []store.Bookmark
+	Filtered   int
+	Duplicates int
+	Invalid    int
+	Err        error
+}
+
+// Fetch downloads a feed, sending the caching headers of the last poll. It
+// returns nil without an error when the feed is unchanged, and the feed with
+// its new caching headers otherwise. The store isn't touched.
+func (p *Poller) Fetch(ctx context.Context, feed store.Feed) (*Parsed, store.Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.Url, nil)
+	if err != nil {
+		return nil, feed, err
+	}
+	if p.UserAgent != "" {
+		req.Header.Set("User-Agent", p.UserAgent)
+	}
+	req.Header.Set("Accept", "application/atom+xml, application/rss+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5")
+	if feed.ETag != "" {
+		req.Header.Set("If-None-Match", feed.ETag)
+	}
+	if feed.LastModified != "" {
+		req.Header.Set("If-Modified-Since", feed.LastModified)
+	}
+
+	client := p.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, feed, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, feed, nil
+	default:
+		return nil, feed, &scrape.StatusError{URL: feed.Url, StatusCode: resp.StatusCode}
+	}
+
+	var body io.Reader = resp.Body
+	if p.MaxBodySize > 0 {
+		body = io.LimitReader(resp.Body, p.MaxBodySize)
+	}
+	parsed, err := Parse(body)
+	if err != nil {
+		return nil, feed, errors.Join(fmt.Errorf("%s isn't a feed", feed.Url), err)
+	}
+	// after redirects, relative links are relative to where the feed is now
+	parsed.resolveLinks(resp.Request.URL)
+	feed.ETag = resp.Header.Get("ETag")
+	feed.LastModified = resp.Header.Get("Last-Modified")
+	return parsed, feed, nil
+}
+
+var errDryRun = errors.New("dry run")
+
+// Poll fetches a feed and bookmarks the items it hasn't seen before, tagged
+// with the feed's title and tags. Failures are recorded with the feed and
+// returned in the result.
+func (p *Poller) Poll(ctx context.Context, feed store.Feed) Result {
+	result := Result{Feed: feed}
+	parsed, fetched, err := p.Fetch(ctx, feed)
+	if err == nil && parsed == nil {
+		result.NotModified = true
+	}
+	if err == nil {
+		err = p.save(&result, fetched, parsed)
+	}
+	if err != nil {
+		result.Err = err
+		feed.LastError = err.Error()
+		if recordErr := store.RecordPoll(p.DB, feed); recordErr != nil {
+			result.Err = errors.Join(err, recordErr)
+		}
+	}
+	return result
+}
+
+func (p *Poller) save(result *Result, feed store.Feed, parsed *Parsed) error {
+	var filter *regexp.Regexp
+	if feed.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile("(?i)" + feed.Filter); err != nil {
+			return errors.Join(fmt.Errorf("invalid filter %q", feed.Filter), err)
+		}
+	}
+
+	first := feed.PolledAt.IsZero()
+	feed.PolledAt = time.Now()
+	feed.LastError = ""
+	if parsed != nil && feed.Title == "" {
+		feed.Title = parsed.Title
+	}
+	result.Feed = feed
+
+	err := store.Transaction(p.DB, func(tx *store.Tx) error {
+		if parsed != nil {
+			result.Items = len(parsed.Items)
+			for _, item := range parsed.Items {
+				if err := p.saveItem(tx, result, feed, item, first, filter); err != nil {
+					return err
+				}
+			}
+		}
+		if p.DryRun {
+			return errDryRun
+		}
+		return store.RecordPoll(tx, feed)
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return err
+}
+
+func (p *Poller) saveItem(tx *store.Tx, result *Result, feed store.Feed, item Item, first bool, filter *regexp.Regexp) error {
+	if item.ID == "" {
+		return nil
+	}
+	seen, err := store.SeenFeedItem(tx, feed.Url, item.ID)
+	if err != nil || seen {
+		return err
+	}
+	link, err := store.NormalizeURL(item.URL)
+	if err != nil {
+		// not seen yet, so it is bookmarked once the feed fixes its link
+		result.Invalid++
+		return nil
+	}
+	result.New++
+	if err := store.MarkFeedItemSeen(tx, feed.Url, item.ID); err != nil {
+		return err
+	}
+	if first && !p.Backfill {
+		return nil
+	}
+	if filter != nil && !filter.MatchString(item.Title+"\n"+item.URL+"\n"+item.Summary) {
+		result.Filtered++
+		return nil
+	}
+	bm := store.Bookmark{
+		Url:         link,
+		OriginalUrl: link,
+		Title:       item.Title,
+		Description: item.Summary,
+		Tags:        store.MergeTags([]string{feed.Title}, feed.Tags),
+		Unread:      true,
+	}
+	if p.Rules != nil {
+		bm = p.Rules.Apply(bm)
+	}
+	id, err := store.InsertBookmark(tx, bm)
+	if errors.Is(err, store.ErrDuplicate) {
+		result.Duplicates++
+		return nil
+	}
+	if err != nil {
+		return errors.Join(fmt.Errorf("unable to save %s", link), err)
+	}
+	bm.Id = id
+	result.Added = append(result.Added, bm)
+	if p.Enrich {
+		return store.EnqueueEnrichment(tx, id, "original")
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package feeds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+	"github.com/lukasmwerner/mark/store/storetest"
+)
+
+// thirdPost is added to the atom fixture by feedServer.publish.
+const thirdPost = `<entry>
+    <title>Third post</title>
+    <id>tag:example.com,2024:3</id>
+    <link href="posts/3"/>
+    <summary>More about feeds</summary>
+  </entry>
+  <entry>`
+
+type feedServer struct {
+	*httptest.Server
+	body     string
+	etag     string
+	requests int
+}
+
+// newFeedServer serves the atom fixture at /blog/atom.xml with an ETag,
+// answering 304 when it is sent back unchanged.
+func newFeedServer(t *testing.T) *feedServer {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "atom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &feedServer{body: string(data), etag: `"v1"`}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests++
+		if r.URL.Path != "/blog/atom.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Header().Set("ETag", s.etag)
+		w.Write([]byte(s.body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// publish adds a third post to the feed.
+func (s *feedServer) publish() {
+	s.body = strings.Replace(s.body, "<entry>", thirdPost, 1)
+	s.etag = `"v2"`
+}
+
+func (s *feedServer) feedURL() string { return s.URL + "/blog/atom.xml" }
+
+func TestFetch(t *testing.T) {
+	server := newFeedServer(t)
+	p := &Poller{HTTP: server.Client()}
+
+	parsed, feed, err := p.Fetch(context.Background(), store.Feed{Url: server.feedURL()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed == nil || len(parsed.Items) != 2 {
+		t.Fatalf("got %+v", parsed)
+	}
+	// links are relative to the feed's own link
+	if parsed.Items[0].URL != server.URL+"/blog/posts/2" || parsed.Items[1].URL != server.URL+"/blog/posts/1" {
+		t.Errorf("got item urls %s and %s", parsed.Items[0].URL, parsed.Items[1].URL)
+	}
+	if feed.ETag != `"v1"` {
+		t.Errorf("got etag %s", feed.ETag)
+	}
+
+	parsed, feed, err = p.Fetch(context.Background(), feed)
+	if err != nil || parsed != nil {
+		t.Errorf("got %+v and %v for an unchanged feed", parsed, err)
+	}
+
+	server.publish()
+	parsed, feed, err = p.Fetch(context.Background(), feed)
+	if err != nil || parsed == nil || len(parsed.Items) != 3 || feed.ETag != `"v2"` {
+		t.Errorf("got %+v, etag %s and %v for a changed feed", parsed, feed.ETag, err)
+	}
+}
+
+func TestFetchNotAFeed(t *testing.T) {
+	server := newFeedServer(t)
+	p := &Poller{HTTP: server.Client()}
+	if _, _, err := p.Fetch(context.Background(), store.Feed{Url: server.URL + "/missing"}); err == nil {
+		t.Error("fetched a missing feed")
+	}
+}
+
+// poll polls the feed as it is saved in the store.
+func poll(t *testing.T, p *Poller, url string) Result {
+	t.Helper()
+	feed, err := store.GetFeed(p.DB, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := p.Poll(context.Background(), feed)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	return result
+}
+
+func addedURLs(result Result) []string {
+	urls := []string{}
+	for _, bm := range result.Added {
+		urls = append(urls, bm.Url)
+	}
+	return urls
+}
+
+func TestPoll(t *testing.T) {
+	server := newFeedServer(t)
+	db := storetest.Open(t)
+	p := &Poller{DB: db, HTTP: server.Client()}
+	if err := store.AddFeed(db, store.Feed{Url: server.feedURL(), Tags: []string{"blogs"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the items already there on the first poll are only remembered
+	result := poll(t, p, server.feedURL())
+	if result.Items != 2 || result.New != 2 || len(result.Added) != 0 {
+		t.Errorf("first poll: got %d items, %d new and added %q", result.Items, result.New, addedURLs(result))
+	}
+	if result.Feed.Title != "Example Blog" {
+		t.Errorf("got title %q", result.Feed.Title)
+	}
+
+	if result := poll(t, p, server.feedURL()); !result.NotModified {
+		t.Errorf("polled an unchanged feed again: %+v", result)
+	}
+
+	server.publish()
+	result = poll(t, p, server.feedURL())
+	if result.New != 1 || !reflect.DeepEqual(addedURLs(result), []string{server.URL + "/blog/posts/3"}) {
+		t.Fatalf("got %d new and added %q", result.New, addedURLs(result))
+	}
+	bm, err := store.FindBookmarkByURL(db, server.URL+"/blog/posts/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.Title != "Third post" || bm.Description != "More about feeds" || !bm.Unread {
+		t.Errorf("got %+v", bm)
+	}
+	if !reflect.DeepEqual(bm.Tags, []string{"Example Blog", "blogs"}) {
+		t.Errorf("got tags %q", bm.Tags)
+	}
+}
+
+func TestPollBackfill(t *testing.T) {
+	server := newFeedServer(t)
+	db := storetest.Open(t)
+	p := &Poller{DB: db, HTTP: server.Client(), Backfill: true}
+	if err := store.AddFeed(db, store.Feed{Url: server.feedURL()}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := poll(t, p, server.feedURL())
+	want := []string{server.URL + "/blog/posts/2", server.URL + "/blog/posts/1"}
+	if !reflect.DeepEqual(addedURLs(result), want) {
+		t.Errorf("added %q", addedURLs(result))
+	}
+}
+
+func TestPollFilter(t *testing.T) {
+	server := newFeedServer(t)
+	db := storetest.Open(t)
+	p := &Poller{DB: db, HTTP: server.Client(), Backfill: true}
+	if err := store.AddFeed(db, store.Feed{Url: server.feedURL(), Filter: "SQLITE"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the filter matches the summary, ignoring case
+	result := poll(t, p, server.feedURL())
+	if result.Filtered != 1 || !reflect.DeepEqual(addedURLs(result), []string{server.URL + "/blog/posts/2"}) {
+		t.Errorf("filtered %d and added %q", result.Filtered, addedURLs(result))
+	}
+
+	// filtered items are seen too, and not bookmarked when the feed changes
+	server.publish()
+	result = poll(t, p, server.feedURL())
+	if result.New != 1 || result.Filtered != 1 || len(result.Added) != 0 {
+		t.Errorf("got %d new, filtered %d and added %q", result.New, result.Filtered, addedURLs(result))
+	}
+}
+
+func TestPollDryRun(t *testing.T) {
+	server := newFeedServer(t)
+	db := storetest.Open(t)
+	p := &Poller{DB: db, HTTP: server.Client(), Backfill: true, DryRun: true}
+	if err := store.AddFeed(db, store.Feed{Url: server.feedURL()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := poll(t, p, server.feedURL()); len(result.Added) != 2 {
+		t.Errorf("would add %q", addedURLs(result))
+	}
+	p.DryRun = false
+	if result := poll(t, p, server.feedURL()); len(result.Added) != 2 {
+		t.Errorf("the dry run saved something, added %q", addedURLs(result))
+	}
+}
+
+func TestPollInvalidLink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Links</title>
+<entry><title>No link</title><id>tag:example.com,2024:nolink</id></entry></feed>`))
+	}))
+	defer server.Close()
+	db := storetest.Open(t)
+	p := &Poller{DB: db, HTTP: server.Client(), Backfill: true}
+	if err := store.AddFeed(db, store.Feed{Url: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the item is tried again on every poll until it has a link
+	for i := 0; i < 2; i++ {
+		if result := poll(t, p, server.URL); result.New != 0 || result.Invalid != 1 {
+			t.Errorf("poll %d: got %d new and %d invalid", i+1, result.New, result.Invalid)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Buku reads the sqlite database of buku, usually
+// ~/.local/share/buku/bookmarks.db. Buku doesn't keep track of when
+// bookmarks were added.
+type Buku struct{}
+
+func (*Buku) Name() string { return "buku" }
+
+func (*Buku) Description() string {
+	return "Imports the bookmarks.db of buku"
+}
+
+func (*Buku) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	// sqlite needs a file, and reading from a copy leaves buku's alone
+	tmp, err := os.MkdirTemp("", "mark-buku-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	copied := filepath.Join(tmp, "bookmarks.db")
+	f, err := os.Create(copied)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", copied)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT URL, COALESCE(metadata, ''), COALESCE(tags, ''), COALESCE(desc, '') FROM bookmarks ORDER BY id;`)
+	if err != nil {
+		return nil, errors.Join(errors.New("not a buku database"), err)
+	}
+	defer rows.Close()
+
+	bookmarks := []store.Bookmark{}
+	for rows.Next() {
+		var bm store.Bookmark
+		var tags string
+		if err := rows.Scan(&bm.Url, &bm.Title, &tags, &bm.Description); err != nil {
+			return nil, err
+		}
+		// tags are stored as ,a,b,
+		bm.Tags = store.NormalizeTags(strings.Split(tags, ","))
+		bookmarks = append(bookmarks, bm)
+	}
+	return bookmarks, rows.Err()
+}
@@ -0,0 +1,35 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuku(t *testing.T) {
+	bookmarks := parseFixture(t, "buku", "buku.db", ParseOptions{})
+	if len(bookmarks) != 2 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+
+	a := bookmarks[0]
+	if a.Url != "https://example.com/a" || a.Title != "Example" || a.Description != "A description" {
+		t.Errorf("got url %q, title %q and description %q", a.Url, a.Title, a.Description)
+	}
+	// buku keeps tags as ,go,tools,
+	if !reflect.DeepEqual(a.Tags, []string{"go", "tools"}) {
+		t.Errorf("got tags %q", a.Tags)
+	}
+	// buku has no dates
+	if !a.CreatedAt.IsZero() {
+		t.Errorf("got created at %s", a.CreatedAt)
+	}
+
+	if b := bookmarks[1]; b.Title != "" || b.Description != "" || len(b.Tags) != 0 {
+		t.Errorf("got title %q, description %q and tags %q", b.Title, b.Description, b.Tags)
+	}
+
+	if _, err := (&Buku{}).Parse(strings.NewReader("not a database"), ParseOptions{}); err == nil {
+		t.Error("read a file that isn't a buku database")
+	}
+}
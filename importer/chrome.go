@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+type chromeNode struct {
+	ID           string       `json:"id"`
+	GUID         string       `json:"guid"`
+	Type         string       `json:"type"`
+	Name         string       `json:"name"`
+	URL          string       `json:"url"`
+	DateAdded    string       `json:"date_added"`
+	DateModified string       `json:"date_modified"`
+	Children     []chromeNode `json:"children"`
+}
+
+type chromeFile struct {
+	Roots map[string]chromeNode `json:"roots"`
+}
+
+// ChromeProfile finds the default profile of the first Chromium based
+// browser installed, trying Chrome, Chromium and Brave.
+func ChromeProfile() (string, error) {
+	for _, dir := range chromeDirs() {
+		profile := filepath.Join(dir, "Default")
+		if _, err := os.Stat(filepath.Join(profile, "Bookmarks")); err == nil {
+			return profile, nil
+		}
+	}
+	return "", errors.New("unable to find a chrome profile, use --profile")
+}
+
+func chromeDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "darwin":
+		support := filepath.Join(home, "Library", "Application Support")
+		return []string{
+			filepath.Join(support, "Google", "Chrome"),
+			filepath.Join(support, "Chromium"),
+			filepath.Join(support, "BraveSoftware", "Brave-Browser"),
+		}
+	case "windows":
+		local := os.Getenv("LOCALAPPDATA")
+		return []string{
+			filepath.Join(local, "Google", "Chrome", "User Data"),
+			filepath.Join(local, "Chromium", "User Data"),
+			filepath.Join(local, "BraveSoftware", "Brave-Browser", "User Data"),
+		}
+	default:
+		config := filepath.Join(home, ".config")
+		return []string{
+			filepath.Join(config, "google-chrome"),
+			filepath.Join(config, "chromium"),
+			filepath.Join(config, "BraveSoftware", "Brave-Browser"),
+		}
+	}
+}
+
+// ReadChrome reads the Bookmarks file of a Chrome (or other Chromium based
+// browser) profile, along with the guids it keeps for the bookmarks. profile
+// may also be the Bookmarks file itself.
+func ReadChrome(profile string, opts ParseOptions) ([]store.Bookmark, []string, error) {
+	file := profile
+	if info, err := os.Stat(profile); err == nil && info.IsDir() {
+		file = filepath.Join(profile, "Bookmarks")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("unable to read %s", file), err)
+	}
+
+	var parsed chromeFile
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("%s is not a chrome bookmarks file", file), err)
+	}
+
+	// the roots (bookmark bar, other, mobile) are the browser's own folders
+	// and don't become tags
+	roots := []string{}
+	for name := range parsed.Roots {
+		roots = append(roots, name)
+	}
+	sort.Strings(roots)
+
+	bookmarks := []store.Bookmark{}
+	guids := []string{}
+	var walk func(node chromeNode, folders []string)
+	walk = func(node chromeNode, folders []string) {
+		switch node.Type {
+		case "url":
+			bm := store.Bookmark{
+				Url:       node.URL,
+				Title:     node.Name,
+				CreatedAt: chromeTime(node.DateAdded),
+				UpdatedAt: chromeTime(node.DateModified),
+				Tags:      []string{},
+			}
+			if bm.UpdatedAt.IsZero() {
+				bm.UpdatedAt = bm.CreatedAt
+			}
+			if opts.FolderTags {
+				bm.Tags = store.NormalizeTags(folders)
+			}
+			bookmarks = append(bookmarks, bm)
+			// older versions only have the id, which is stable too
+			guid := node.GUID
+			if guid == "" {
+				guid = node.ID
+			}
+			guids = append(guids, guid)
+		case "folder":
+			for _, child := range node.Children {
+				walk(child, append(append([]string{}, folders...), node.Name))
+			}
+		}
+	}
+	for _, name := range roots {
+		for _, child := range parsed.Roots[name].Children {
+			walk(child, nil)
+		}
+	}
+	return bookmarks, guids, nil
+}
+
+// chromeTime converts the microseconds since 1601 chrome stores.
+func chromeTime(value string) time.Time {
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || micros <= 0 {
+		return time.Time{}
+	}
+	const epochDelta = 11644473600 // seconds from 1601 to 1970
+	return time.UnixMicro(micros - epochDelta*1e6)
+}
@@ -0,0 +1,52 @@
+package importer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadChrome(t *testing.T) {
+	bookmarks, guids, err := ReadChrome(filepath.Join("testdata", "chrome_bookmarks.json"), ParseOptions{FolderTags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookmarks) != 3 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+	// the bookmark without a guid falls back to its id
+	want := []string{"6f1c9a52-0d6b-4a56-9d0e-3c1b7a0e9c11", "a2d4e6f8-1b3c-4d5e-8f90-123456789abc", "9"}
+	if !reflect.DeepEqual(guids, want) {
+		t.Errorf("got guids %q", guids)
+	}
+
+	golang := bookmarks[0]
+	if golang.Url != "https://go.dev/" || golang.Title != "The Go Programming Language" {
+		t.Errorf("got url %q and title %q", golang.Url, golang.Title)
+	}
+	// the bookmarks bar is a root and isn't a tag
+	if !reflect.DeepEqual(golang.Tags, []string{"Programming"}) {
+		t.Errorf("got tags %q", golang.Tags)
+	}
+	created := time.UnixMicro(1700526400000000)
+	if !golang.CreatedAt.Equal(created) || !golang.UpdatedAt.Equal(created.Add(100*time.Second)) {
+		t.Errorf("got created at %s and updated at %s", golang.CreatedAt, golang.UpdatedAt)
+	}
+
+	article := bookmarks[1]
+	if len(article.Tags) != 0 || !article.UpdatedAt.Equal(article.CreatedAt) {
+		t.Errorf("got tags %q and updated at %s", article.Tags, article.UpdatedAt)
+	}
+	if undated := bookmarks[2]; undated.Url != "https://example.org/" || !undated.CreatedAt.IsZero() {
+		t.Errorf("got url %q and created at %s", undated.Url, undated.CreatedAt)
+	}
+
+	bookmarks, _, err = ReadChrome(filepath.Join("testdata", "chrome_bookmarks.json"), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookmarks[0].Tags) != 0 {
+		t.Errorf("got tags %q without folder tags", bookmarks[0].Tags)
+	}
+}
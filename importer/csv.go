@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+// readCSV reads a csv file with a header row into a map of column to value
+// for every row. Column names are lower cased, and the header must have the
+// required ones to tell an export apart from any other csv file.
+func readCSV(r io.Reader, required ...string) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty csv file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+	for _, column := range required {
+		if !slices.Contains(header, column) {
+			return nil, errors.New("missing the " + column + " column")
+		}
+	}
+
+	rows := []map[string]string{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := map[string]string{}
+		for i, value := range record {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// isHTML peeks at the start of r to tell html exports from csv ones.
+func isHTML(r *bufio.Reader) bool {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+		case 0xef:
+			// a byte order mark
+			r.Discard(3)
+		default:
+			return b[0] == '<'
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// parseFixture parses the file in testdata with the registered format.
+func parseFixture(t *testing.T, format string, name string, opts ParseOptions) []store.Bookmark {
+	t.Helper()
+	f, ok := Lookup(format)
+	if !ok {
+		t.Fatalf("no format %s", format)
+	}
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	bookmarks, err := f.Parse(file, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bookmarks
+}
+
+func TestReadCSV(t *testing.T) {
+	input := "\ufeff URL ,Title,Extra\nhttps://example.com,\"A, \"\"quoted\"\" title\"\nhttps://example.org\nhttps://example.net, Three ,x,unnamed\n"
+	rows, err := readCSV(strings.NewReader(input), "url", "title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{
+		{"url": "https://example.com", "title": `A, "quoted" title`},
+		{"url": "https://example.org"},
+		{"url": "https://example.net", "title": "Three", "extra": "x"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %q", rows)
+	}
+
+	if _, err := readCSV(strings.NewReader("link,name\nhttps://example.com,A\n"), "url"); err == nil {
+		t.Error("read a csv file without the url column")
+	}
+	if _, err := readCSV(strings.NewReader(""), "url"); err == nil {
+		t.Error("read an empty file")
+	}
+}
+
+func TestIsHTML(t *testing.T) {
+	for input, want := range map[string]bool{
+		"\ufeff\n  <!DOCTYPE html>": true,
+		"<h1>Unread</h1>":           true,
+		"title,url\n":               false,
+		"":                          false,
+	} {
+		if got := isHTML(bufio.NewReader(strings.NewReader(input))); got != want {
+			t.Errorf("isHTML(%q) = %v", input, got)
+		}
+	}
+}
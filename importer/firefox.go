@@ -0,0 +1,288 @@
+package importer
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The guids of the folders Firefox creates itself.
+const (
+	firefoxRoot = "root________"
+	firefoxTags = "tags________"
+)
+
+var firefoxRoots = map[string]bool{
+	firefoxRoot:    true,
+	"menu________": true,
+	"toolbar_____": true,
+	"unfiled_____": true,
+	"mobile______": true,
+	firefoxTags:    true,
+}
+
+// FirefoxProfile finds the default Firefox profile from profiles.ini.
+func FirefoxProfile() (string, error) {
+	dir, err := firefoxDir()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(filepath.Join(dir, "profiles.ini"))
+	if err != nil {
+		return "", errors.Join(errors.New("unable to find a firefox profile, use --profile"), err)
+	}
+	defer f.Close()
+
+	sections := parseINI(f)
+	// the profile the installed firefox uses wins over the one marked as
+	// default, which older versions used
+	profile := ""
+	for name, section := range sections {
+		if strings.HasPrefix(name, "Install") && section["Default"] != "" {
+			profile = section["Default"]
+			break
+		}
+	}
+	if profile == "" {
+		for name, section := range sections {
+			if strings.HasPrefix(name, "Profile") && section["Default"] == "1" {
+				profile = section["Path"]
+				break
+			}
+		}
+	}
+	if profile == "" {
+		return "", errors.New("no default firefox profile in profiles.ini, use --profile")
+	}
+	if filepath.IsAbs(profile) {
+		return profile, nil
+	}
+	return filepath.Join(dir, filepath.FromSlash(profile)), nil
+}
+
+func firefoxDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "Firefox"), nil
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "Mozilla", "Firefox"), nil
+	default:
+		return filepath.Join(home, ".mozilla", "firefox"), nil
+	}
+}
+
+// parseINI reads the sections of an ini file into maps of their keys.
+func parseINI(r io.Reader) map[string]map[string]string {
+	sections := map[string]map[string]string{}
+	current := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = map[string]string{}
+			sections[line[1:len(line)-1]] = current
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return sections
+}
+
+type firefoxItem struct {
+	id       int64
+	parent   int64
+	kind     int
+	title    string
+	url      string
+	added    int64
+	modified int64
+	place    int64
+	guid     string
+}
+
+// ReadFirefox reads the bookmarks from the places.sqlite of a Firefox
+// profile, with their tags, keywords and descriptions, along with the guids
+// Firefox keeps for them. Firefox keeps the database locked while running,
+// so it is read from a copy.
+func ReadFirefox(profile string, opts ParseOptions) ([]store.Bookmark, []string, error) {
+	places := profile
+	if info, err := os.Stat(profile); err == nil && info.IsDir() {
+		places = filepath.Join(profile, "places.sqlite")
+	}
+
+	tmp, err := os.MkdirTemp("", "mark-firefox-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(tmp)
+	copied := filepath.Join(tmp, "places.sqlite")
+	if err := copyFile(places, copied); err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("unable to read %s", places), err)
+	}
+	// recent changes may still be in the write ahead log
+	if err := copyFile(places+"-wal", copied+"-wal"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	db, err := sql.Open("sqlite3", copied)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	items := map[int64]*firefoxItem{}
+	rows, err := db.Query(`SELECT b.id, b.parent, b.type, COALESCE(b.title, ''), COALESCE(p.url, ''),
+		COALESCE(b.dateAdded, 0), COALESCE(b.lastModified, 0), COALESCE(b.fk, 0), COALESCE(b.guid, '')
+	FROM moz_bookmarks b LEFT JOIN moz_places p ON p.id = b.fk ORDER BY b.parent, b.position;`)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("not a firefox places database"), err)
+	}
+	order := []*firefoxItem{}
+	for rows.Next() {
+		item := &firefoxItem{}
+		err := rows.Scan(&item.id, &item.parent, &item.kind, &item.title, &item.url, &item.added, &item.modified, &item.place, &item.guid)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		items[item.id] = item
+		order = append(order, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	descriptions, err := firefoxStrings(db, `SELECT id, description FROM moz_places WHERE description IS NOT NULL;`)
+	if err != nil {
+		// older versions of firefox didn't keep descriptions
+		descriptions = map[int64][]string{}
+	}
+	keywords, err := firefoxStrings(db, `SELECT place_id, keyword FROM moz_keywords;`)
+	if err != nil {
+		keywords = map[int64][]string{}
+	}
+
+	// tags are folders in the tags root holding a bookmark for every place
+	// with that tag
+	tags := map[int64][]string{}
+	for _, item := range order {
+		folder := items[item.parent]
+		if item.kind != 1 || folder == nil || items[folder.parent] == nil || items[folder.parent].guid != firefoxTags {
+			continue
+		}
+		tags[item.place] = append(tags[item.place], folder.title)
+	}
+
+	bookmarks := []store.Bookmark{}
+	guids := []string{}
+	for _, item := range order {
+		if item.kind != 1 || strings.HasPrefix(item.url, "place:") || firefoxUnder(items, item, firefoxTags) {
+			continue
+		}
+		bm := store.Bookmark{
+			Url:       item.url,
+			Title:     item.title,
+			CreatedAt: firefoxTime(item.added),
+			UpdatedAt: firefoxTime(item.modified),
+			Tags:      store.MergeTags(tags[item.place], keywords[item.place]),
+		}
+		if d := descriptions[item.place]; len(d) > 0 {
+			bm.Description = d[0]
+		}
+		if opts.FolderTags {
+			bm.Tags = store.MergeTags(bm.Tags, firefoxFolders(items, item))
+		}
+		bookmarks = append(bookmarks, bm)
+		guids = append(guids, item.guid)
+	}
+	return bookmarks, guids, nil
+}
+
+func firefoxStrings(db *sql.DB, query string) (map[int64][]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := map[int64][]string{}
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			values[id] = append(values[id], value)
+		}
+	}
+	return values, rows.Err()
+}
+
+// firefoxTime converts the microseconds firefox stores.
+func firefoxTime(micros int64) time.Time {
+	if micros <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(micros)
+}
+
+func firefoxUnder(items map[int64]*firefoxItem, item *firefoxItem, guid string) bool {
+	for parent := items[item.parent]; parent != nil; parent = items[parent.parent] {
+		if parent.guid == guid {
+			return true
+		}
+		if parent.parent == parent.id {
+			break
+		}
+	}
+	return false
+}
+
+// firefoxFolders lists the folders an item is in, outermost first, leaving
+// out the ones firefox creates itself.
+func firefoxFolders(items map[int64]*firefoxItem, item *firefoxItem) []string {
+	names := []string{}
+	for parent := items[item.parent]; parent != nil && parent.guid != firefoxRoot; parent = items[parent.parent] {
+		if !firefoxRoots[parent.guid] && parent.title != "" {
+			names = append([]string{parent.title}, names...)
+		}
+		if parent.parent == parent.id {
+			break
+		}
+	}
+	return names
+}
+
+func copyFile(from string, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
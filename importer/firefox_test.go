@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadFirefox(t *testing.T) {
+	bookmarks, guids, err := ReadFirefox(filepath.Join("testdata", "places.sqlite"), ParseOptions{FolderTags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the place: query and the entries of the tag folders aren't bookmarks
+	if len(bookmarks) != 2 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+	if !reflect.DeepEqual(guids, []string{"bookmarkArt_", "bookmarkGo__"}) {
+		t.Errorf("got guids %q", guids)
+	}
+
+	article := bookmarks[0]
+	if article.Url != "https://example.com/article" || article.Description != "Worth reading twice" {
+		t.Errorf("got url %q and description %q", article.Url, article.Description)
+	}
+	// the menu is one of firefox's own folders and isn't a tag
+	if !reflect.DeepEqual(article.Tags, []string{"reading"}) {
+		t.Errorf("got tags %q", article.Tags)
+	}
+	if !article.CreatedAt.IsZero() {
+		t.Errorf("got created at %s for an undated bookmark", article.CreatedAt)
+	}
+
+	golang := bookmarks[1]
+	if golang.Url != "https://go.dev/" || golang.Title != "Go" {
+		t.Errorf("got url %q and title %q", golang.Url, golang.Title)
+	}
+	if !reflect.DeepEqual(golang.Tags, []string{"golang", "Programming"}) {
+		t.Errorf("got tags %q", golang.Tags)
+	}
+	if !golang.CreatedAt.Equal(time.UnixMicro(1700000200000000)) || !golang.UpdatedAt.Equal(time.UnixMicro(1700000300000000)) {
+		t.Errorf("got created at %s and updated at %s", golang.CreatedAt, golang.UpdatedAt)
+	}
+
+	bookmarks, _, err = ReadFirefox(filepath.Join("testdata", "places.sqlite"), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bookmarks[1].Tags, []string{"golang"}) {
+		t.Errorf("got tags %q without folder tags", bookmarks[1].Tags)
+	}
+}
@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Format reads the export file of a browser or bookmark manager.
+type Format interface {
+	// Name is what the format is called on the command line.
+	Name() string
+	// Description says which exports the format reads.
+	Description() string
+	Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error)
+}
+
+var registry = []Format{}
+
+// Register adds a format to the ones mark import offers.
+func Register(f Format) {
+	registry = append(registry, f)
+}
+
+// Registered returns a copy of the registered formats.
+func Registered() []Format {
+	return append([]Format{}, registry...)
+}
+
+// Lookup finds the registered format with the name.
+func Lookup(name string) (Format, bool) {
+	for _, f := range registry {
+		if f.Name() == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func init() {
+	Register(&Netscape{})
+	Register(&JSON{})
+	Register(&Pinboard{})
+	Register(&Pocket{})
+	Register(&Raindrop{})
+	Register(&Linkding{})
+	Register(&Shaarli{})
+	Register(&Buku{})
+}
+
+// Netscape is the bookmarks.html format read by ParseNetscape.
+type Netscape struct{}
+
+func (*Netscape) Name() string { return "html" }
+
+func (*Netscape) Description() string {
+	return "Imports a bookmarks.html file exported by a browser"
+}
+
+func (*Netscape) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	return ParseNetscape(r, opts)
+}
+
+// parseTime reads the RFC 3339 times most apis export, falling back to unix
+// timestamps.
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t
+	}
+	return parseTimestamp(value)
+}
+
+// readBool reads the many ways exports spell true.
+func readBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "y", "on":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+// Package importer reads bookmarks exported from browsers and other bookmark
+// managers and saves them in the store.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lukasmwerner/mark/rules"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// ParseOptions control how bookmarks are read from an export.
+type ParseOptions struct {
+	// FolderTags tags every bookmark with the names of the folders it is in.
+	// The browser's own root folders, like the bookmarks toolbar, are left
+	// out.
+	FolderTags bool
+}
+
+// Options control how parsed bookmarks are saved.
+type Options struct {
+	// Rules tag the bookmarks like mark add does, they may be nil.
+	Rules *rules.Rules
+	// Tags are added to every imported bookmark.
+	Tags []string
+	// Enrich queues the bookmarks for mark enrich to fetch their metadata.
+	// They keep the url they were imported with.
+	Enrich bool
+	// DryRun only counts what would be imported.
+	DryRun bool
+	// Source names where the bookmarks came from, like a browser profile,
+	// and SourceIds are the ids it keeps for them, in the same order. The
+	// ids are recorded after the import, and with Incremental bookmarks
+	// imported from the source before are left out, however old they are.
+	Source      string
+	SourceIds   []string
+	Incremental bool
+}
+
+// Result counts what happened to the bookmarks of an import.
+type Result struct {
+	Added      int
+	Duplicates int
+	Invalid    int
+	// Seen counts the bookmarks left out of an incremental import because
+	// they were imported from the source before.
+	Seen int
+	// Updated and Unchanged count the bookmarks RestoreJSON found under their
+	// id, depending on whether the export differed.
+	Updated   int
+	Unchanged int
+	// Skipped explains every bookmark that wasn't added.
+	Skipped []error
+}
+
+var errDryRun = errors.New("dry run")
+
+// Save adds the bookmarks to the store in a single transaction, so the whole
+// import syncs as one batch. Bookmarks whose url is already bookmarked, or
+// appears earlier in the import, are skipped, as are invalid urls.
+func Save(db *store.DB, bookmarks []store.Bookmark, opts Options) (Result, error) {
+	var result Result
+	err := store.Transaction(db, func(tx *store.Tx) error {
+		seen := map[string]bool{}
+		if opts.Source != "" && opts.Incremental {
+			var err error
+			if seen, err = store.ImportedItems(tx, opts.Source); err != nil {
+				return err
+			}
+		}
+
+		imported := []string{}
+		for i, bm := range bookmarks {
+			item := ""
+			if i < len(opts.SourceIds) {
+				item = opts.SourceIds[i]
+			}
+			if item != "" && seen[item] {
+				result.Seen++
+				continue
+			}
+
+			link, err := store.NormalizeURL(bm.Url)
+			if err != nil {
+				result.Invalid++
+				result.Skipped = append(result.Skipped, err)
+				continue
+			}
+			bm.Url = link
+			if bm.OriginalUrl == "" {
+				bm.OriginalUrl = link
+			}
+			bm.Tags = store.MergeTags(bm.Tags, opts.Tags)
+			if opts.Rules != nil {
+				bm = opts.Rules.Apply(bm)
+			}
+
+			id, err := store.InsertBookmark(tx, bm)
+			if skipDuplicate(&result, link, err) {
+				// bookmarked already, which is as good as imported
+				if item != "" {
+					imported = append(imported, item)
+				}
+				continue
+			}
+			if err != nil {
+				return errors.Join(fmt.Errorf("unable to save %s", link), err)
+			}
+			result.Added++
+			if item != "" {
+				imported = append(imported, item)
+			}
+
+			if opts.Enrich {
+				if err := store.EnqueueEnrichment(tx, id, "original"); err != nil {
+					return err
+				}
+			}
+		}
+		if opts.DryRun {
+			// roll back, having checked for duplicates within the import too
+			return errDryRun
+		}
+		if opts.Source != "" {
+			return store.RecordImportedItems(tx, opts.Source, imported)
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return result, nil
+	}
+	return result, err
+}
+
+// parseTimestamp reads a unix timestamp, which some exports give in
+// milliseconds or microseconds instead of seconds.
+func parseTimestamp(value string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	switch {
+	case n > 1e14:
+		return time.UnixMicro(n)
+	case n > 1e11:
+		return time.UnixMilli(n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+	"github.com/lukasmwerner/mark/store/storetest"
+)
+
+func TestSaveIncremental(t *testing.T) {
+	db := storetest.Open(t)
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	bookmarks := []store.Bookmark{
+		{Url: "https://example.com/a", CreatedAt: old},
+		{Url: "https://example.com/b"},
+	}
+	opts := Options{Source: "firefox:/profile", SourceIds: []string{"guid-a", "guid-b"}, Incremental: true}
+
+	result, err := Save(db, bookmarks, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 2 || result.Seen != 0 {
+		t.Errorf("first import: added %d, seen %d", result.Added, result.Seen)
+	}
+
+	// a bookmark synced into the browser later keeps its old date, and
+	// undated ones have none, they are imported all the same
+	bookmarks = append(bookmarks, store.Bookmark{Url: "https://example.com/c", CreatedAt: old.Add(-time.Hour)}, store.Bookmark{Url: "https://example.com/d"})
+	opts.SourceIds = append(opts.SourceIds, "guid-c", "guid-d")
+	result, err = Save(db, bookmarks, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 2 || result.Seen != 2 || result.Duplicates != 0 {
+		t.Errorf("second import: added %d, seen %d, %d duplicates", result.Added, result.Seen, result.Duplicates)
+	}
+
+	// a bookmark deleted from the store isn't imported again
+	a, err := store.FindBookmarkByURL(db, "https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteBookmark(db, a.Id); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = Save(db, bookmarks, opts); err != nil || result.Added != 0 || result.Seen != 4 {
+		t.Errorf("third import: added %d, seen %d, %v", result.Added, result.Seen, err)
+	}
+
+	// other profiles and full imports don't skip anything
+	opts.Source = "chrome:/profile"
+	if result, err = Save(db, bookmarks, opts); err != nil || result.Added != 1 || result.Duplicates != 3 {
+		t.Errorf("other profile: added %d, %d duplicates, %v", result.Added, result.Duplicates, err)
+	}
+}
+
+func TestSaveDryRunRecordsNothing(t *testing.T) {
+	db := storetest.Open(t)
+	bookmarks := []store.Bookmark{{Url: "https://example.com/a"}}
+	opts := Options{Source: "chrome:/profile", SourceIds: []string{"1"}, Incremental: true, DryRun: true}
+	if _, err := Save(db, bookmarks, opts); err != nil {
+		t.Fatal(err)
+	}
+	opts.DryRun = false
+	if result, err := Save(db, bookmarks, opts); err != nil || result.Added != 1 {
+		t.Errorf("added %d after a dry run, %v", result.Added, err)
+	}
+}
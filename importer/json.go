@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// JSON reads the json documents and json lines written by mark export json,
+// keeping the ids of the bookmarks so RestoreJSON can put them back in place.
+type JSON struct{}
+
+func (*JSON) Name() string { return "json" }
+
+func (*JSON) Description() string {
+	return "Imports a json export of mark"
+}
+
+func (*JSON) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	bookmarks := []store.Bookmark{}
+	err := ReadJSON(r, func(b store.Bookmark) error {
+		bookmarks = append(bookmarks, b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+var errNotJSONExport = errors.New("not a mark json export")
+
+// ReadJSON decodes a json document or json lines written by mark export json,
+// calling fn with every bookmark as soon as it is read, so large exports
+// don't have to fit in memory. An error from fn stops reading. The header
+// has to come before the bookmarks, as mark writes it.
+func ReadJSON(r io.Reader, fn func(store.Bookmark) error) error {
+	dec := json.NewDecoder(r)
+	count := 0
+	read := func() error {
+		count++
+		var b exporter.JSONBookmark
+		if err := dec.Decode(&b); err != nil {
+			return errors.Join(fmt.Errorf("unable to read bookmark %d", count), err)
+		}
+		return fn(b.Bookmark())
+	}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return errors.Join(errNotJSONExport, err)
+	}
+	var header exporter.JSONHeader
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return errors.Join(errNotJSONExport, err)
+		}
+		switch token {
+		case "format":
+			err = dec.Decode(&header.Format)
+		case "version":
+			err = dec.Decode(&header.Version)
+		case "bookmarks":
+			// a json document, the bookmarks are read one by one from
+			// the array
+			if err := checkJSONHeader(header); err != nil {
+				return err
+			}
+			if err := expectDelim(dec, '['); err != nil {
+				return errors.Join(errNotJSONExport, err)
+			}
+			for dec.More() {
+				if err := read(); err != nil {
+					return err
+				}
+			}
+			err = expectDelim(dec, ']')
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return errors.Join(errNotJSONExport, err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return errors.Join(errNotJSONExport, err)
+	}
+	if err := checkJSONHeader(header); err != nil {
+		return err
+	}
+
+	// in json lines every bookmark after the header is a value of its own
+	for dec.More() {
+		if err := read(); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.Join(fmt.Errorf("unable to read bookmark %d", count+1), err)
+	}
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %s, found %v", delim, token)
+	}
+	return nil
+}
+
+func checkJSONHeader(header exporter.JSONHeader) error {
+	if header.Format != "mark" {
+		return errNotJSONExport
+	}
+	if header.Version > exporter.JSONVersion {
+		return fmt.Errorf("the export has version %d of the format, this version of mark reads up to %d", header.Version, exporter.JSONVersion)
+	}
+	return nil
+}
+
+// RestoreJSON saves the bookmarks of a json export of mark under their own
+// ids, so a backup can be restored into a store that already has some of
+// them without making duplicates. Bookmarks with an id that is taken are
+// replaced by the exported version, and the others are added as they were,
+// timestamps included. Rules aren't applied, the bookmarks are restored as
+// exported. They are decoded one at a time inside the transaction, so large
+// exports don't have to fit in memory.
+func RestoreJSON(db *store.DB, r io.Reader, opts Options) (Result, error) {
+	var result Result
+	err := store.Transaction(db, func(tx *store.Tx) error {
+		err := ReadJSON(r, func(bm store.Bookmark) error {
+			return restoreBookmark(tx, &result, bm, opts)
+		})
+		if err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return result, nil
+	}
+	return result, err
+}
+
+func restoreBookmark(tx *store.Tx, result *Result, bm store.Bookmark, opts Options) error {
+	link, err := store.NormalizeURL(bm.Url)
+	if err != nil {
+		result.Invalid++
+		result.Skipped = append(result.Skipped, err)
+		return nil
+	}
+	bm.Url = link
+	bm.Tags = store.MergeTags(bm.Tags, opts.Tags)
+
+	if bm.Id == 0 {
+		// written by hand, or by another tool
+		id, err := store.InsertBookmark(tx, bm)
+		if skipDuplicate(result, link, err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Join(fmt.Errorf("unable to save %s", link), err)
+		}
+		result.Added++
+		if opts.Enrich {
+			return store.EnqueueEnrichment(tx, id, "original")
+		}
+		return nil
+	}
+
+	existing, err := store.GetBookmark(tx, bm.Id)
+	found := err == nil
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	if found && sameJSON(existing, bm) {
+		result.Unchanged++
+		return nil
+	}
+
+	err = store.RestoreBookmark(tx, bm)
+	if skipDuplicate(result, link, err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Join(fmt.Errorf("unable to restore bookmark %d", bm.Id), err)
+	}
+	if found {
+		result.Updated++
+		return nil
+	}
+	result.Added++
+	if opts.Enrich {
+		return store.EnqueueEnrichment(tx, bm.Id, "original")
+	}
+	return nil
+}
+
+func skipDuplicate(result *Result, link string, err error) bool {
+	if err == nil {
+		return false
+	}
+	var dup *store.DuplicateError
+	if !errors.As(err, &dup) {
+		return false
+	}
+	result.Duplicates++
+	result.Skipped = append(result.Skipped, fmt.Errorf("%s is already bookmarked as %d", link, dup.Existing.Id))
+	return true
+}
+
+// sameJSON compares bookmarks by their json form, which leaves out what the
+// store computes and how times are stored.
+func sameJSON(a store.Bookmark, b store.Bookmark) bool {
+	ja, errA := json.Marshal(exporter.ToJSON(a))
+	jb, errB := json.Marshal(exporter.ToJSON(b))
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
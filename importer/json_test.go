@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestReadJSON(t *testing.T) {
+	created := time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)
+	bookmarks := []store.Bookmark{
+		{Id: 1, Url: "https://example.com", Title: "Example", Tags: []string{"a"}, Notes: "read later", Unread: true, CreatedAt: created, UpdatedAt: created},
+		{Id: 2, Url: "https://example.org/<b>", Tags: []string{}, Stars: 3, Duration: time.Minute},
+	}
+	for _, lines := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := exporter.WriteJSON(&buf, bookmarks, exporter.JSONOptions{Lines: lines}); err != nil {
+			t.Fatal(err)
+		}
+		read, err := (&JSON{}).Parse(&buf, ParseOptions{})
+		if err != nil {
+			t.Fatalf("lines %v: %s", lines, err)
+		}
+		if !reflect.DeepEqual(read, bookmarks) {
+			t.Errorf("lines %v: got %+v", lines, read)
+		}
+	}
+}
+
+func TestReadJSONStreams(t *testing.T) {
+	layouts := map[string][2]string{
+		"document": {`{"format":"mark","version":1,"bookmarks":[` + "\n" + `{"id":1,"url":"https://example.com"}`, ",\n" + `{"id":2,"url":"https://example.org"}` + "\n]}\n"},
+		"lines":    {`{"format":"mark","version":1}` + "\n" + `{"id":1,"url":"https://example.com"}`, "\n" + `{"id":2,"url":"https://example.org"}` + "\n"},
+	}
+	for name, parts := range layouts {
+		r, w := io.Pipe()
+		read := make(chan store.Bookmark)
+		done := make(chan error)
+		go func() {
+			done <- ReadJSON(r, func(b store.Bookmark) error {
+				read <- b
+				return nil
+			})
+		}()
+
+		// the first bookmark is read before the rest of the export is there
+		go io.WriteString(w, parts[0])
+		if b := <-read; b.Id != 1 {
+			t.Errorf("%s: read bookmark %d first", name, b.Id)
+		}
+		go func() {
+			io.WriteString(w, parts[1])
+			w.Close()
+		}()
+		if b := <-read; b.Id != 2 {
+			t.Errorf("%s: read bookmark %d second", name, b.Id)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestReadJSONHeader(t *testing.T) {
+	tests := map[string]string{
+		"no header":     `{"id":1,"url":"https://example.com"}`,
+		"not mark":      `{"format":"other","version":1,"bookmarks":[]}`,
+		"too new":       `{"format":"mark","version":99}`,
+		"not an object": `[{"format":"mark"}]`,
+	}
+	for name, input := range tests {
+		if _, err := (&JSON{}).Parse(strings.NewReader(input), ParseOptions{}); err == nil {
+			t.Errorf("%s: read %s", name, input)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Linkding reads the bookmarks returned by linkding's /api/bookmarks/, either
+// the whole paged response or just its results.
+type Linkding struct{}
+
+type linkdingBookmark struct {
+	URL                string   `json:"url"`
+	Title              string   `json:"title"`
+	Description        string   `json:"description"`
+	Notes              string   `json:"notes"`
+	WebsiteTitle       string   `json:"website_title"`
+	WebsiteDescription string   `json:"website_description"`
+	Unread             bool     `json:"unread"`
+	TagNames           []string `json:"tag_names"`
+	DateAdded          string   `json:"date_added"`
+	DateModified       string   `json:"date_modified"`
+}
+
+func (*Linkding) Name() string { return "linkding" }
+
+func (*Linkding) Description() string {
+	return "Imports bookmarks saved from the linkding api"
+}
+
+func (*Linkding) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []linkdingBookmark
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var page struct {
+			Results []linkdingBookmark `json:"results"`
+		}
+		err = json.Unmarshal(trimmed, &page)
+		results = page.Results
+	} else {
+		err = json.Unmarshal(trimmed, &results)
+	}
+	if err != nil {
+		return nil, errors.Join(errors.New("not a linkding json export"), err)
+	}
+
+	bookmarks := []store.Bookmark{}
+	for _, b := range results {
+		// the title and description are the ones set by hand, linkding
+		// falls back to the ones it scraped
+		bm := store.Bookmark{
+			Url:         strings.TrimSpace(b.URL),
+			Title:       b.Title,
+			Description: b.Description,
+			Notes:       b.Notes,
+			CreatedAt:   parseTime(b.DateAdded),
+			UpdatedAt:   parseTime(b.DateModified),
+			Tags:        store.NormalizeTags(b.TagNames),
+			Unread:      b.Unread,
+		}
+		if bm.Title == "" {
+			bm.Title = b.WebsiteTitle
+		}
+		if bm.Description == "" {
+			bm.Description = b.WebsiteDescription
+		}
+		bookmarks = append(bookmarks, bm)
+	}
+	return bookmarks, nil
+}
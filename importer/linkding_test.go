@@ -0,0 +1,43 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkding(t *testing.T) {
+	bookmarks := parseFixture(t, "linkding", "linkding.json", ParseOptions{})
+	if len(bookmarks) != 2 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+
+	// without a title and description of its own the scraped ones are used
+	a := bookmarks[0]
+	if a.Url != "https://example.com/a" || a.Title != "Scraped title" || a.Description != "Scraped description" {
+		t.Errorf("got url %q, title %q and description %q", a.Url, a.Title, a.Description)
+	}
+	if a.Notes != "Some notes" || !a.Unread || !reflect.DeepEqual(a.Tags, []string{"go", "tools"}) {
+		t.Errorf("got notes %q, unread %v and tags %q", a.Notes, a.Unread, a.Tags)
+	}
+	if !a.CreatedAt.Equal(time.Date(2024, 3, 1, 9, 30, 0, 123456000, time.UTC)) {
+		t.Errorf("got created at %s", a.CreatedAt)
+	}
+	if !a.UpdatedAt.Equal(time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("got updated at %s", a.UpdatedAt)
+	}
+
+	if b := bookmarks[1]; b.Title != "My title" || b.Description != "My description" || b.Unread {
+		t.Errorf("got title %q, description %q and unread %v", b.Title, b.Description, b.Unread)
+	}
+
+	// just the results of a page
+	results, err := (&Linkding{}).Parse(strings.NewReader(`[{"url":"https://example.com","tag_names":["a"]}]`), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Url != "https://example.com" {
+		t.Errorf("got %+v", results)
+	}
+}
@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"io"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// rootFolderAttrs mark the folders browsers create themselves.
+var rootFolderAttrs = []string{"personal_toolbar_folder", "unfiled_bookmarks_folder"}
+
+// ParseNetscape reads the bookmarks.html format every browser exports:
+//
+//	<DL><p>
+//	    <DT><H3 ADD_DATE="1700000000">Folder</H3>
+//	    <DL><p>
+//	        <DT><A HREF="https://example.com" ADD_DATE="1700000000" TAGS="a,b">Title</A>
+//	        <DD>Description
+//	    </DL><p>
+//	</DL><p>
+func ParseNetscape(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks := []store.Bookmark{}
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		bm := store.Bookmark{
+			Url:       strings.TrimSpace(attr(a, "href")),
+			Title:     strings.TrimSpace(a.Text()),
+			CreatedAt: parseTimestamp(attr(a, "add_date")),
+			UpdatedAt: parseTimestamp(attr(a, "last_modified")),
+			Tags:      store.NormalizeTags(strings.Split(attr(a, "tags"), ",")),
+		}
+
+		// the html parser puts the <DD> next to the <DT> holding the link
+		dt := a.ParentsFiltered("dt").First()
+		if dd := dt.Next(); dd.Is("dd") {
+			dd = dd.Clone()
+			dd.Find("dl").Remove()
+			bm.Description = strings.TrimSpace(dd.Text())
+		}
+
+		if opts.FolderTags {
+			bm.Tags = store.MergeTags(bm.Tags, folders(a))
+		}
+		bookmarks = append(bookmarks, bm)
+	})
+	return bookmarks, nil
+}
+
+// folders lists the names of the folders a link is in, outermost first. A
+// folder is an <H3> followed by the <DL> holding its contents.
+func folders(a *goquery.Selection) []string {
+	names := []string{}
+	a.ParentsFiltered("dl").Each(func(_ int, dl *goquery.Selection) {
+		h3 := dl.PrevAllFiltered("h3").First()
+		if h3.Length() == 0 || isRootFolder(h3) {
+			return
+		}
+		if name := strings.TrimSpace(h3.Text()); name != "" {
+			names = append([]string{name}, names...)
+		}
+	})
+	return names
+}
+
+func isRootFolder(h3 *goquery.Selection) bool {
+	for _, name := range rootFolderAttrs {
+		if strings.EqualFold(attr(h3, name), "true") {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(s *goquery.Selection, name string) string {
+	value, _ := s.Attr(name)
+	return value
+}
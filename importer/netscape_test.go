@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestNetscapeRoundTrip(t *testing.T) {
+	created := time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)
+	updated := created.Add(36 * time.Hour)
+	bookmarks := []store.Bookmark{
+		{Url: "https://example.com/?a=1&b=<2>", Title: `Tom & "Jerry" <3`, Description: "Two lines\nof <b>text</b> & more", Tags: []string{"cartoons", "tv"}, CreatedAt: created, UpdatedAt: updated},
+		{Url: "https://example.org", Title: "Untagged"},
+	}
+
+	for _, folders := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := exporter.WriteNetscape(&buf, bookmarks, exporter.NetscapeOptions{FolderPerTag: folders}); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ParseNetscape(&buf, ParseOptions{FolderTags: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// with folders a bookmark is in the folder of each of its tags
+		byURL := map[string]store.Bookmark{}
+		for _, b := range read {
+			byURL[b.Url] = b
+		}
+		if len(byURL) != len(bookmarks) {
+			t.Fatalf("folders %v: read %d bookmarks: %+v", folders, len(byURL), read)
+		}
+		for _, want := range bookmarks {
+			got, ok := byURL[want.Url]
+			if !ok {
+				t.Errorf("folders %v: lost %s", folders, want.Url)
+				continue
+			}
+			if got.Title != want.Title || got.Description != want.Description {
+				t.Errorf("folders %v: got title %q and description %q", folders, got.Title, got.Description)
+			}
+			if !reflect.DeepEqual(store.NormalizeTags(got.Tags), store.NormalizeTags(want.Tags)) {
+				t.Errorf("folders %v: got tags %q, want %q", folders, got.Tags, want.Tags)
+			}
+			if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+				t.Errorf("folders %v: got created %s and updated %s", folders, got.CreatedAt, got.UpdatedAt)
+			}
+		}
+	}
+}
+
+func TestNetscapeLeavesOut(t *testing.T) {
+	bookmark := store.Bookmark{
+		Url:         "https://example.com",
+		Title:       "  Padded  ",
+		Notes:       "mine",
+		Unread:      true,
+		OriginalUrl: "https://example.com/?utm_source=x",
+		Authors:     []string{"someone"},
+	}
+	var buf bytes.Buffer
+	if err := exporter.WriteNetscape(&buf, []store.Bookmark{bookmark}, exporter.NetscapeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ParseNetscape(&buf, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := store.Bookmark{Url: "https://example.com", Title: "Padded", Tags: []string{}}
+	if len(read) != 1 || !reflect.DeepEqual(read[0], want) {
+		t.Errorf("got %+v, want only %+v", read, want)
+	}
+}
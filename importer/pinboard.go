@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Pinboard reads the json export of pinboard.in, which is also what its
+// posts/all api returns. Pinboard calls the title the description and the
+// description extended.
+type Pinboard struct{}
+
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Extended    string `json:"extended"`
+	Time        string `json:"time"`
+	ToRead      string `json:"toread"`
+	Tags        string `json:"tags"`
+}
+
+func (*Pinboard) Name() string { return "pinboard" }
+
+func (*Pinboard) Description() string {
+	return "Imports the json export of pinboard.in"
+}
+
+func (*Pinboard) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	var posts []pinboardPost
+	if err := json.NewDecoder(r).Decode(&posts); err != nil {
+		return nil, errors.Join(errors.New("not a pinboard json export"), err)
+	}
+
+	bookmarks := []store.Bookmark{}
+	for _, post := range posts {
+		bookmarks = append(bookmarks, store.Bookmark{
+			Url:         strings.TrimSpace(post.Href),
+			Title:       strings.TrimSpace(post.Description),
+			Description: strings.TrimSpace(post.Extended),
+			CreatedAt:   parseTime(post.Time),
+			Tags:        store.NormalizeTags(strings.Fields(post.Tags)),
+			Unread:      readBool(post.ToRead),
+		})
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,31 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPinboard(t *testing.T) {
+	bookmarks := parseFixture(t, "pinboard", "pinboard.json", ParseOptions{})
+	if len(bookmarks) != 2 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+
+	// pinboard's description is the title and extended the description
+	a := bookmarks[0]
+	if a.Url != "https://example.com/a" || a.Title != "Example" || a.Description != "A longer description" {
+		t.Errorf("got url %q, title %q and description %q", a.Url, a.Title, a.Description)
+	}
+	// tags are separated by spaces
+	if !reflect.DeepEqual(a.Tags, []string{"go", "tools"}) || !a.Unread {
+		t.Errorf("got tags %q and unread %v", a.Tags, a.Unread)
+	}
+	if !a.CreatedAt.Equal(time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("got created at %s", a.CreatedAt)
+	}
+
+	if b := bookmarks[1]; b.Unread || len(b.Tags) != 0 {
+		t.Errorf("got unread %v and tags %q", b.Unread, b.Tags)
+	}
+}
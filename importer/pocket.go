@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Pocket reads both exports Pocket has offered. The older html one lists the
+// saves under an "Unread" and a "Read Archive" heading:
+//
+//	<h1>Unread</h1>
+//	<ul>
+//	  <li><a href="https://example.com" time_added="1700000000" tags="a,b">Title</a></li>
+//	</ul>
+//
+// The newer csv one has title, url, time_added, tags (separated by |) and
+// status (unread or archive) columns.
+type Pocket struct{}
+
+func (*Pocket) Name() string { return "pocket" }
+
+func (*Pocket) Description() string {
+	return "Imports the html or csv export of Pocket"
+}
+
+func (*Pocket) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	br := bufio.NewReader(r)
+	if isHTML(br) {
+		return parsePocketHTML(br)
+	}
+	return parsePocketCSV(br)
+}
+
+func parsePocketHTML(r io.Reader) ([]store.Bookmark, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks := []store.Bookmark{}
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		heading := a.ParentsFiltered("ul").First().PrevAllFiltered("h1").First()
+		title := strings.TrimSpace(a.Text())
+		link := strings.TrimSpace(attr(a, "href"))
+		if title == link {
+			// pocket uses the url when it never got a title
+			title = ""
+		}
+		bookmarks = append(bookmarks, store.Bookmark{
+			Url:       link,
+			Title:     title,
+			CreatedAt: parseTimestamp(attr(a, "time_added")),
+			Tags:      store.NormalizeTags(strings.Split(attr(a, "tags"), ",")),
+			Unread:    !strings.EqualFold(strings.TrimSpace(heading.Text()), "read archive"),
+		})
+	})
+	return bookmarks, nil
+}
+
+func parsePocketCSV(r io.Reader) ([]store.Bookmark, error) {
+	rows, err := readCSV(r, "url", "time_added")
+	if err != nil {
+		return nil, errors.Join(errors.New("not a pocket export"), err)
+	}
+
+	bookmarks := []store.Bookmark{}
+	for _, row := range rows {
+		title := row["title"]
+		if title == row["url"] {
+			title = ""
+		}
+		bookmarks = append(bookmarks, store.Bookmark{
+			Url:       row["url"],
+			Title:     title,
+			CreatedAt: parseTimestamp(row["time_added"]),
+			Tags:      store.NormalizeTags(strings.Split(row["tags"], "|")),
+			Unread:    row["status"] != "archive",
+		})
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPocketCSV(t *testing.T) {
+	bookmarks := parseFixture(t, "pocket", "pocket.csv", ParseOptions{})
+	if len(bookmarks) != 3 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+
+	a := bookmarks[0]
+	if a.Url != "https://example.com/a" || a.Title != "Example, with a comma" || !a.Unread {
+		t.Errorf("got url %q, title %q and unread %v", a.Url, a.Title, a.Unread)
+	}
+	if !reflect.DeepEqual(a.Tags, []string{"go", "tools"}) {
+		t.Errorf("got tags %q", a.Tags)
+	}
+	if !a.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("got created at %s", a.CreatedAt)
+	}
+
+	// pocket uses the url as the title of pages it never got one for
+	if b := bookmarks[1]; b.Title != "" || b.Unread || len(b.Tags) != 0 {
+		t.Errorf("got title %q, unread %v and tags %q", b.Title, b.Unread, b.Tags)
+	}
+
+	c := bookmarks[2]
+	if c.Title != `Quoted "title"` || !reflect.DeepEqual(c.Tags, []string{"a", "b"}) {
+		t.Errorf("got title %q and tags %q", c.Title, c.Tags)
+	}
+	// timestamps in milliseconds
+	if !c.CreatedAt.Equal(time.Unix(1700000200, 0)) {
+		t.Errorf("got created at %s", c.CreatedAt)
+	}
+}
+
+func TestPocketHTML(t *testing.T) {
+	bookmarks := parseFixture(t, "pocket", "pocket.html", ParseOptions{})
+	if len(bookmarks) != 3 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+
+	a := bookmarks[0]
+	if a.Title != "Example" || !a.Unread || !reflect.DeepEqual(a.Tags, []string{"go", "tools"}) {
+		t.Errorf("got title %q, unread %v and tags %q", a.Title, a.Unread, a.Tags)
+	}
+	if !a.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("got created at %s", a.CreatedAt)
+	}
+	if b := bookmarks[1]; b.Title != "" || len(b.Tags) != 0 {
+		t.Errorf("got title %q and tags %q", b.Title, b.Tags)
+	}
+	if c := bookmarks[2]; c.Unread || !reflect.DeepEqual(c.Tags, []string{"done"}) {
+		t.Errorf("the archived one has unread %v and tags %q", c.Unread, c.Tags)
+	}
+}
@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Raindrop reads the csv export of raindrop.io, with id, title, note,
+// excerpt, url, folder, tags, created, cover, highlights and favorite
+// columns. The collection a raindrop is in becomes a tag like a browser
+// folder does, nested collections are separated by /.
+type Raindrop struct{}
+
+func (*Raindrop) Name() string { return "raindrop" }
+
+func (*Raindrop) Description() string {
+	return "Imports the csv export of raindrop.io"
+}
+
+func (*Raindrop) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	rows, err := readCSV(r, "url", "title")
+	if err != nil {
+		return nil, errors.Join(errors.New("not a raindrop.io csv export"), err)
+	}
+
+	bookmarks := []store.Bookmark{}
+	for _, row := range rows {
+		bm := store.Bookmark{
+			Url:         row["url"],
+			Title:       row["title"],
+			Description: row["excerpt"],
+			Notes:       row["note"],
+			CreatedAt:   parseTime(row["created"]),
+			Tags:        store.NormalizeTags(strings.Split(row["tags"], ",")),
+		}
+		if highlights := row["highlights"]; highlights != "" {
+			bm.Notes = strings.TrimSpace(bm.Notes + "\n\n" + highlights)
+		}
+		if readBool(row["favorite"]) {
+			bm.Tags = store.MergeTags(bm.Tags, []string{"favorite"})
+		}
+		if opts.FolderTags && row["folder"] != "" && row["folder"] != "Unsorted" {
+			bm.Tags = store.MergeTags(bm.Tags, strings.Split(row["folder"], "/"))
+		}
+		bookmarks = append(bookmarks, bm)
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,39 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRaindrop(t *testing.T) {
+	bookmarks := parseFixture(t, "raindrop", "raindrop.csv", ParseOptions{FolderTags: true})
+	if len(bookmarks) != 2 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+
+	a := bookmarks[0]
+	if a.Url != "https://example.com/a" || a.Title != "Example" || a.Description != "The excerpt" {
+		t.Errorf("got url %q, title %q and description %q", a.Url, a.Title, a.Description)
+	}
+	if a.Notes != "My note\n\nHighlighted text" {
+		t.Errorf("got notes %q", a.Notes)
+	}
+	// favorites and the nested collection become tags
+	if !reflect.DeepEqual(a.Tags, []string{"go", "tools", "favorite", "Reading", "Go"}) {
+		t.Errorf("got tags %q", a.Tags)
+	}
+	if !a.CreatedAt.Equal(time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("got created at %s", a.CreatedAt)
+	}
+
+	// the Unsorted collection isn't a tag
+	if b := bookmarks[1]; len(b.Tags) != 0 || b.Notes != "" {
+		t.Errorf("got tags %q and notes %q", b.Tags, b.Notes)
+	}
+
+	bookmarks = parseFixture(t, "raindrop", "raindrop.csv", ParseOptions{})
+	if !reflect.DeepEqual(bookmarks[0].Tags, []string{"go", "tools", "favorite"}) {
+		t.Errorf("got tags %q without folder tags", bookmarks[0].Tags)
+	}
+}
@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Shaarli reads the links returned by Shaarli's /api/v1/links. Shaarli's
+// notes without a url link to themselves with a relative url, and are
+// skipped as invalid.
+type Shaarli struct{}
+
+type shaarliLink struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Created     string   `json:"created"`
+	Updated     string   `json:"updated"`
+}
+
+func (*Shaarli) Name() string { return "shaarli" }
+
+func (*Shaarli) Description() string {
+	return "Imports links saved from the Shaarli api"
+}
+
+func (*Shaarli) Parse(r io.Reader, opts ParseOptions) ([]store.Bookmark, error) {
+	var links []shaarliLink
+	if err := json.NewDecoder(r).Decode(&links); err != nil {
+		return nil, errors.Join(errors.New("not a shaarli json export"), err)
+	}
+
+	bookmarks := []store.Bookmark{}
+	for _, link := range links {
+		bookmarks = append(bookmarks, store.Bookmark{
+			Url:         strings.TrimSpace(link.URL),
+			Title:       link.Title,
+			Description: link.Description,
+			CreatedAt:   parseTime(link.Created),
+			UpdatedAt:   parseTime(link.Updated),
+			Tags:        store.NormalizeTags(link.Tags),
+		})
+	}
+	return bookmarks, nil
+}
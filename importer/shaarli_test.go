@@ -0,0 +1,30 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShaarli(t *testing.T) {
+	bookmarks := parseFixture(t, "shaarli", "shaarli.json", ParseOptions{})
+	if len(bookmarks) != 2 {
+		t.Fatalf("got %d bookmarks: %+v", len(bookmarks), bookmarks)
+	}
+
+	a := bookmarks[0]
+	if a.Url != "https://example.com/a" || a.Title != "Example" || a.Description != "A description" {
+		t.Errorf("got url %q, title %q and description %q", a.Url, a.Title, a.Description)
+	}
+	if !reflect.DeepEqual(a.Tags, []string{"go", "tools"}) {
+		t.Errorf("got tags %q", a.Tags)
+	}
+	if !a.CreatedAt.Equal(time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)) || !a.UpdatedAt.Equal(time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("got created at %s and updated at %s", a.CreatedAt, a.UpdatedAt)
+	}
+
+	// notes link to themselves, which Save skips as invalid
+	if note := bookmarks[1]; note.Url != "/shaare/def456" || !note.UpdatedAt.IsZero() {
+		t.Errorf("got url %q and updated at %s", note.Url, note.UpdatedAt)
+	}
+}
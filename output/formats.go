@@ -0,0 +1,104 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/store"
+	"gopkg.in/yaml.v3"
+)
+
+// JSON writes a json object per line, with the fields of mark export json.
+type JSON struct{}
+
+func (*JSON) Name() string        { return "json" }
+func (*JSON) Description() string { return "one json object per line (json lines)" }
+
+func (*JSON) Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, bookmark := range bookmarks {
+		if err := enc.Encode(exporter.ToJSON(bookmark)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSV writes the title, description, tags and url of every bookmark.
+type CSV struct{}
+
+func (*CSV) Name() string        { return "csv" }
+func (*CSV) Description() string { return "title, description, tags and url as csv" }
+
+func (*CSV) Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Title", "Description", "Tags", "URL"})
+	for _, bookmark := range bookmarks {
+		cw.Write([]string{bookmark.Title, bookmark.Description, strings.Join(bookmark.Tags, ","), bookmark.Url})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// YAML writes a yaml sequence of bookmarks.
+type YAML struct{}
+
+func (*YAML) Name() string        { return "yaml" }
+func (*YAML) Description() string { return "a yaml list of every field" }
+
+func (*YAML) Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(toJSON(bookmarks)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// TOML writes every bookmark as a [[bookmark]] table.
+type TOML struct{}
+
+func (*TOML) Name() string        { return "toml" }
+func (*TOML) Description() string { return "a [[bookmark]] table of every field for each bookmark" }
+
+func (*TOML) Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error {
+	if len(bookmarks) == 0 {
+		return nil
+	}
+	return toml.NewEncoder(w).Encode(struct {
+		Bookmark []exporter.JSONBookmark `toml:"bookmark"`
+	}{toJSON(bookmarks)})
+}
+
+// toJSON converts the bookmarks to the form mark export json writes, which
+// the yaml and toml formats use as well.
+func toJSON(bookmarks []store.Bookmark) []exporter.JSONBookmark {
+	converted := []exporter.JSONBookmark{}
+	for _, bookmark := range bookmarks {
+		converted = append(converted, exporter.ToJSON(bookmark))
+	}
+	return converted
+}
+
+// Markdown writes a list of links with the template of mark export markdown.
+type Markdown struct{}
+
+func (*Markdown) Name() string        { return "markdown" }
+func (*Markdown) Description() string { return "a markdown list of links" }
+
+func (*Markdown) Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error {
+	templates := opts.Templates
+	if templates == nil {
+		templates = exporter.Templates("")
+	}
+	return exporter.WriteMarkdown(w, bookmarks, exporter.TemplateOptions{
+		Title:     opts.Title,
+		GroupBy:   opts.GroupBy,
+		Templates: templates,
+	})
+}
@@ -0,0 +1,129 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/store"
+)
+
+func format(t *testing.T, mode string, bookmarks []store.Bookmark, opts Options) string {
+	t.Helper()
+	formatter, err := Lookup(mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, bookmarks, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+var created = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func TestJSONMatchesExport(t *testing.T) {
+	bookmarks := []store.Bookmark{
+		{Id: 1, Url: "https://example.com/a?b&c", Title: "A", Tags: []string{"go"}, CreatedAt: created},
+		{Id: 2, Url: "https://example.com/b", Title: "B"},
+	}
+	lines := strings.Split(strings.TrimSpace(format(t, "json", bookmarks, Options{})), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	for i, line := range lines {
+		var got exporter.JSONBookmark
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		if want := exporter.ToJSON(bookmarks[i]); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+	if !strings.Contains(lines[0], `"url":"https://example.com/a?b&c"`) {
+		t.Errorf("escaped the url: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"tags":[]`) || strings.Contains(lines[1], "created_at") {
+		t.Errorf("got %s, want empty tags and no created_at", lines[1])
+	}
+}
+
+func TestYAMLAndTOMLFields(t *testing.T) {
+	bookmarks := []store.Bookmark{
+		{Id: 1, Url: "https://example.com/a", Title: "A", Tags: []string{"go"}, CreatedAt: created},
+		{Id: 2, Url: "https://example.com/b", Title: "B"},
+	}
+	for mode, tags := range map[string]string{"yaml": "tags: []", "toml": "tags = []"} {
+		t.Run(mode, func(t *testing.T) {
+			got := format(t, mode, bookmarks, Options{})
+			if !strings.Contains(got, tags) {
+				t.Errorf("has no %q:\n%s", tags, got)
+			}
+			if strings.Count(got, "created_at") != 1 || strings.Contains(got, "0001-01-01") {
+				t.Errorf("wrote unknown times:\n%s", got)
+			}
+			if strings.Contains(got, "CreatedAt") || strings.Contains(got, "Url") {
+				t.Errorf("used the go field names:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestMarkdownMatchesExport(t *testing.T) {
+	bookmarks := []store.Bookmark{
+		{Url: "https://example.com/a (b)", Title: "A [link]", Description: "one\ntwo", Tags: []string{"go"}},
+		{Url: "https://example.com/c"},
+	}
+	got := format(t, "markdown", bookmarks, Options{})
+	want := `- [A \[link\]](https://example.com/a%20%28b%29) - one two
+- [https://example.com/c](https://example.com/c)
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	opts := Options{Title: "Bookmarks", GroupBy: exporter.GroupByTag}
+	var export bytes.Buffer
+	err := exporter.WriteMarkdown(&export, bookmarks, exporter.TemplateOptions{
+		Title:     opts.Title,
+		GroupBy:   opts.GroupBy,
+		Templates: exporter.Templates(""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := format(t, "markdown", bookmarks, opts); got != export.String() {
+		t.Errorf("got\n%s\nwant the export\n%s", got, export.String())
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	bookmarks := []store.Bookmark{{Url: "https://www.example.com/a", Title: "A [b]", Tags: []string{"a", "b"}, CreatedAt: created}}
+	got := format(t, "template", bookmarks, Options{Template: `{{domain .Url}} {{md .Title}} {{join .Tags ","}} {{date .CreatedAt}}`})
+	if want := "example.com A \\[b\\] a,b 2024-05-01\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJSONNamesMatchBookmark(t *testing.T) {
+	names := func(v any) map[string]bool {
+		names := map[string]bool{}
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			names[name] = true
+		}
+		return names
+	}
+	bookmark := names(store.Bookmark{})
+	for name := range names(exporter.JSONBookmark{}) {
+		if !bookmark[name] {
+			t.Errorf("store.Bookmark has no field %s", name)
+		}
+	}
+}
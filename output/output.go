@@ -0,0 +1,79 @@
+// Package output formats bookmarks for mark show, mark list and mark export,
+// for people to read and for scripts to parse.
+package output
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Options are shared by every formatter, each uses the ones it needs.
+type Options struct {
+	// Template is the text/template the template formatter runs for every
+	// bookmark, DefaultTemplate when empty. It sees the Go names of the
+	// fields of store.Bookmark, not their json names.
+	Template string
+	// Title, GroupBy and Templates are passed to the markdown export template
+	// the markdown formatter renders, see exporter.TemplateOptions. Without
+	// Templates the built in one is used.
+	Title     string
+	GroupBy   string
+	Templates fs.FS
+}
+
+// Formatter writes bookmarks in one format.
+type Formatter interface {
+	// Name is the --mode that picks the formatter.
+	Name() string
+	// Description says in a few words what the format is.
+	Description() string
+	Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error
+}
+
+var registry = []Formatter{}
+
+// Register adds a formatter to the modes of mark show, list and export.
+func Register(f Formatter) {
+	registry = append(registry, f)
+}
+
+// Registered returns a copy of the registered formatters.
+func Registered() []Formatter {
+	return append([]Formatter{}, registry...)
+}
+
+// Lookup finds the formatter for a mode, with an error listing the modes
+// there are when there is none.
+func Lookup(mode string) (Formatter, error) {
+	for _, f := range registry {
+		if f.Name() == mode {
+			return f, nil
+		}
+	}
+	return nil, errors.New("unknown mode " + mode + ", use one of " + strings.Join(Modes(), ","))
+}
+
+// Modes lists the names of the registered formatters, sorted.
+func Modes() []string {
+	names := []string{}
+	for _, f := range registry {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(&JSON{})
+	Register(&CSV{})
+	Register(&YAML{})
+	Register(&TOML{})
+	Register(&Markdown{})
+	Register(&Text{})
+	Register(&Template{})
+}
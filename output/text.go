@@ -0,0 +1,106 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/lukasmwerner/mark/exporter"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Text writes bookmarks the way mark prints them to people.
+type Text struct{}
+
+func (*Text) Name() string        { return "text" }
+func (*Text) Description() string { return "every bookmark as mark add prints it" }
+
+func (*Text) Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error {
+	for i, bookmark := range bookmarks {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if err := PrintBookmark(w, bookmark); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintBookmark writes a bookmark's id and title followed by its urls, tags,
+// description and notes.
+func PrintBookmark(w io.Writer, bookmark store.Bookmark) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %s\n", bookmark.Id, bookmark.Title)
+	fmt.Fprintf(&b, "url:       %s\n", bookmark.Url)
+	if len(bookmark.Redirects) > 0 {
+		chain := append(append([]string{}, bookmark.Redirects...), bookmark.ResolvedUrl)
+		fmt.Fprintf(&b, "redirects: %s\n", strings.Join(chain, " -> "))
+	} else if bookmark.OriginalUrl != "" && bookmark.OriginalUrl != bookmark.Url {
+		fmt.Fprintf(&b, "original:  %s\n", bookmark.OriginalUrl)
+	}
+	if bookmark.CanonicalLink != "" && bookmark.CanonicalLink != bookmark.Url {
+		fmt.Fprintf(&b, "canonical: %s\n", bookmark.CanonicalLink)
+	}
+	if bookmark.LinkedUrl != "" && bookmark.LinkedUrl != bookmark.Url {
+		fmt.Fprintf(&b, "linked:    %s\n", bookmark.LinkedUrl)
+	}
+	fmt.Fprintf(&b, "tags:      %s\n", strings.Join(bookmark.Tags, ", "))
+	if len(bookmark.Authors) > 0 {
+		fmt.Fprintf(&b, "authors:   %s\n", strings.Join(bookmark.Authors, ", "))
+	}
+	if bookmark.Stars > 0 {
+		fmt.Fprintf(&b, "stars:     %d\n", bookmark.Stars)
+	}
+	if bookmark.Duration > 0 {
+		fmt.Fprintf(&b, "duration:  %s\n", bookmark.Duration)
+	}
+	if !bookmark.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, "added:     %s\n", bookmark.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	if bookmark.Unread {
+		fmt.Fprintf(&b, "status:    unread\n")
+	}
+	if bookmark.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", bookmark.Description)
+	}
+	if bookmark.Notes != "" {
+		fmt.Fprintf(&b, "\nnotes:\n%s\n", bookmark.Notes)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// DefaultTemplate is what the template formatter prints without --template:
+// a tab separated id, title and url per bookmark.
+const DefaultTemplate = "{{.Id}}\t{{.Title}}\t{{.Url}}"
+
+// Template runs a text/template for every bookmark.
+type Template struct{}
+
+func (*Template) Name() string        { return "template" }
+func (*Template) Description() string { return "a Go text/template for every bookmark, see --template" }
+
+func (*Template) Format(w io.Writer, bookmarks []store.Bookmark, opts Options) error {
+	text := opts.Template
+	if text == "" {
+		text = DefaultTemplate
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	tmpl, err := template.New("bookmark").Funcs(exporter.TemplateFuncs()).Parse(text)
+	if err != nil {
+		return errors.Join(errors.New("invalid template"), err)
+	}
+	for _, bookmark := range bookmarks {
+		if err := tmpl.Execute(w, bookmark); err != nil {
+			return err
+		}
+	}
+	return nil
+}
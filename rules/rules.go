@@ -0,0 +1,166 @@
+// Package rules tags bookmarks automatically from the rules in rules.toml in
+// the store location. For example:
+//
+//	[[rule]]
+//	tags = ["go"]
+//	domains = ["go.dev", "golang.org"]   # also matches subdomains
+//
+//	[[rule]]
+//	tags = ["go", "library"]
+//	urls = ['github\.com/[^/]+/go-']     # regular expressions
+//	titles = ['(?i)\bgolang\b']          # regular expressions
+//
+//	[[rule]]
+//	tags = ["ml"]
+//	keywords = ["machine learning"]      # scraped keywords, ignoring case
+//
+// A rule applies when each kind of condition it lists has at least one
+// match, so the second rule needs both a matching url and a matching title.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lukasmwerner/mark/store"
+)
+
+// Rule adds its Tags to every bookmark that matches its conditions.
+type Rule struct {
+	Tags     []string `toml:"tags"`
+	Domains  []string `toml:"domains"`
+	URLs     []string `toml:"urls"`
+	Titles   []string `toml:"titles"`
+	Keywords []string `toml:"keywords"`
+
+	urls   []*regexp.Regexp
+	titles []*regexp.Regexp
+}
+
+// Rules is the contents of rules.toml.
+type Rules struct {
+	Rules []Rule `toml:"rule"`
+}
+
+// Load reads the rules from rules.toml in the store location. Having no
+// rules file is not an error, there are just no rules.
+func Load(storeLoc string) (*Rules, error) {
+	var r Rules
+	_, err := toml.DecodeFile(path.Join(storeLoc, "rules.toml"), &r)
+	if errors.Is(err, os.ErrNotExist) {
+		return &r, nil
+	}
+	if err != nil {
+		return nil, errors.Join(errors.New("unable to read rules.toml"), err)
+	}
+
+	for i := range r.Rules {
+		if err := r.Rules[i].compile(); err != nil {
+			return nil, errors.Join(fmt.Errorf("rule %d in rules.toml is invalid", i+1), err)
+		}
+	}
+	return &r, nil
+}
+
+func (r *Rule) compile() error {
+	if len(r.Tags) == 0 {
+		return errors.New("rule has no tags")
+	}
+	if len(r.Domains)+len(r.URLs)+len(r.Titles)+len(r.Keywords) == 0 {
+		return errors.New("rule has no conditions")
+	}
+	var err error
+	if r.urls, err = compileAll(r.URLs); err != nil {
+		return err
+	}
+	r.titles, err = compileAll(r.Titles)
+	return err
+}
+
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Matches reports whether the rule applies to the bookmark.
+func (r *Rule) Matches(b store.Bookmark) bool {
+	if len(r.Domains) > 0 && !matchDomain(b.Url, r.Domains) {
+		return false
+	}
+	if len(r.urls) > 0 && !matchAny(b.Url, r.urls) {
+		return false
+	}
+	if len(r.titles) > 0 && !matchAny(b.Title, r.titles) {
+		return false
+	}
+	if len(r.Keywords) > 0 && !matchKeyword(b.Keywords, r.Keywords) {
+		return false
+	}
+	return true
+}
+
+// Tags returns the tags of every rule that applies to the bookmark which it
+// doesn't have yet.
+func (r *Rules) Tags(b store.Bookmark) []string {
+	tags := []string{}
+	for i := range r.Rules {
+		if r.Rules[i].Matches(b) {
+			tags = append(tags, r.Rules[i].Tags...)
+		}
+	}
+	return store.RemoveTags(tags, b.Tags)
+}
+
+// Apply adds the tags of every rule that applies to the bookmark.
+func (r *Rules) Apply(b store.Bookmark) store.Bookmark {
+	b.Tags = store.MergeTags(b.Tags, r.Tags(b))
+	return b
+}
+
+func matchDomain(rawURL string, domains []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(value string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchKeyword(keywords []string, wanted []string) bool {
+	for _, keyword := range keywords {
+		for _, w := range wanted {
+			if strings.EqualFold(strings.TrimSpace(keyword), strings.TrimSpace(w)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package scrape
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ArXiv gets the authors and abstract of papers from the arXiv api, for both
+// the abstract and pdf pages.
+type ArXiv struct {
+	APIBase string
+}
+
+func (a *ArXiv) Name() string { return "arxiv" }
+
+var arxivPath = regexp.MustCompile(`^/(?:abs|pdf)/(.+?)(?:\.pdf)?$`)
+
+func (a *ArXiv) Match(u *url.URL) bool {
+	return matchHost(u.Hostname(), "arxiv.org") && arxivPath.MatchString(u.Path)
+}
+
+type arxivFeed struct {
+	Entries []struct {
+		Title     string `xml:"title"`
+		Summary   string `xml:"summary"`
+		Published string `xml:"published"`
+		Authors   []struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+func (a *ArXiv) Enrich(ctx context.Context, c *Client, page *Page, md *Metadata) error {
+	id := arxivPath.FindStringSubmatch(page.URL.Path)[1]
+
+	resp, err := c.get(ctx, a.APIBase+"?"+url.Values{"id_list": {id}}.Encode(), "application/atom+xml")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var feed arxivFeed
+	if err := xml.NewDecoder(c.body(resp)).Decode(&feed); err != nil {
+		return err
+	}
+	if len(feed.Entries) == 0 {
+		return errors.New("no paper with id " + id)
+	}
+	entry := feed.Entries[0]
+
+	setIfNotEmpty(&md.Title, collapseSpace(entry.Title))
+	setIfNotEmpty(&md.Description, collapseSpace(entry.Summary))
+	md.SiteName = "arXiv"
+	md.Authors = nil
+	for _, author := range entry.Authors {
+		md.Authors = append(md.Authors, strings.TrimSpace(author.Name))
+	}
+	setIfNotEmpty(&md.Author, strings.Join(md.Authors, ", "))
+	for _, category := range entry.Categories {
+		md.Keywords = append(md.Keywords, category.Term)
+	}
+	if published, err := time.Parse(time.RFC3339, strings.TrimSpace(entry.Published)); err == nil {
+		md.Published = published
+	}
+	return nil
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,111 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HackerNews uses the Hacker News api for item pages to find the article
+// that is being discussed.
+type HackerNews struct {
+	APIBase string
+}
+
+func (h *HackerNews) Name() string { return "hackernews" }
+
+func (h *HackerNews) Match(u *url.URL) bool {
+	return strings.EqualFold(u.Hostname(), "news.ycombinator.com") &&
+		u.Path == "/item" && u.Query().Get("id") != ""
+}
+
+type hackerNewsItem struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	By    string `json:"by"`
+	Time  int64  `json:"time"`
+	Text  string `json:"text"`
+}
+
+func (h *HackerNews) Enrich(ctx context.Context, c *Client, page *Page, md *Metadata) error {
+	id, err := strconv.Atoi(page.URL.Query().Get("id"))
+	if err != nil {
+		return errors.Join(errors.New("invalid item id"), err)
+	}
+
+	var item hackerNewsItem
+	err = c.getJSON(ctx, h.APIBase+"/item/"+strconv.Itoa(id)+".json", &item)
+	if err != nil {
+		return err
+	}
+
+	setIfNotEmpty(&md.Title, item.Title)
+	setIfNotEmpty(&md.Author, item.By)
+	md.SiteName = "Hacker News"
+	md.LinkedURL = item.URL
+	if item.Time != 0 {
+		md.Published = time.Unix(item.Time, 0)
+	}
+	return nil
+}
+
+// Reddit uses the json version of a Reddit post to find the article that is
+// being discussed.
+type Reddit struct {
+	Base string
+}
+
+func (r *Reddit) Name() string { return "reddit" }
+
+func (r *Reddit) Match(u *url.URL) bool {
+	segments := pathSegments(u)
+	return matchHost(u.Hostname(), "reddit.com") &&
+		len(segments) >= 4 && segments[0] == "r" && segments[2] == "comments"
+}
+
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Data struct {
+				Title      string  `json:"title"`
+				URL        string  `json:"url"`
+				Selftext   string  `json:"selftext"`
+				IsSelf     bool    `json:"is_self"`
+				Author     string  `json:"author"`
+				Subreddit  string  `json:"subreddit"`
+				CreatedUTC float64 `json:"created_utc"`
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+func (r *Reddit) Enrich(ctx context.Context, c *Client, page *Page, md *Metadata) error {
+	segments := pathSegments(page.URL)
+	postPath := "/" + strings.Join(segments[:4], "/") + "/.json"
+
+	var listings []redditListing
+	err := c.getJSON(ctx, r.Base+postPath, &listings)
+	if err != nil {
+		return err
+	}
+	if len(listings) == 0 || len(listings[0].Data.Children) == 0 {
+		return errors.New("post not found")
+	}
+	post := listings[0].Data.Children[0].Data
+
+	setIfNotEmpty(&md.Title, post.Title)
+	setIfNotEmpty(&md.Author, post.Author)
+	md.SiteName = "r/" + post.Subreddit
+	if post.IsSelf {
+		setIfNotEmpty(&md.Description, post.Selftext)
+	} else {
+		md.LinkedURL = post.URL
+	}
+	if post.CreatedUTC != 0 {
+		md.Published = time.Unix(int64(post.CreatedUTC), 0)
+	}
+	return nil
+}
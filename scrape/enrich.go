@@ -0,0 +1,97 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Page is what an enricher gets to work with.
+type Page struct {
+	// URL is the address of the page after any redirects.
+	URL *url.URL
+	// Doc is the parsed page, or nil if the page isn't html or couldn't be
+	// fetched.
+	Doc *goquery.Document
+}
+
+// ErrSkipped is returned by an enricher that matched the url but found
+// nothing to work with, such as JSONLD on a page that isn't html.
+var ErrSkipped = errors.New("nothing to enrich")
+
+// Enricher adds site specific metadata on top of the generic html scraping,
+// usually from a structured source like an api.
+type Enricher interface {
+	Name() string
+	Match(u *url.URL) bool
+	Enrich(ctx context.Context, c *Client, page *Page, md *Metadata) error
+}
+
+var registry = []Enricher{}
+
+// Register adds an enricher to the ones used by new clients. Enrichers run
+// in the order they are registered.
+func Register(e Enricher) {
+	registry = append(registry, e)
+}
+
+// Registered returns a copy of the registered enrichers.
+func Registered() []Enricher {
+	return append([]Enricher{}, registry...)
+}
+
+func init() {
+	Register(&JSONLD{})
+	Register(&GitHub{APIBase: "https://api.github.com"})
+	Register(&OEmbed{Providers: DefaultOEmbedProviders})
+	Register(&ArXiv{APIBase: "https://export.arxiv.org/api/query"})
+	Register(&HackerNews{APIBase: "https://hacker-news.firebaseio.com/v0"})
+	Register(&Reddit{Base: "https://www.reddit.com"})
+}
+
+// getJSON fetches rawURL with the client's settings and decodes the json
+// response into v.
+func (c *Client) getJSON(ctx context.Context, rawURL string, v any) error {
+	resp, err := c.get(ctx, rawURL, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(c.body(resp)).Decode(v)
+}
+
+// matchHost reports whether host is domain or one of its subdomains.
+func matchHost(host string, domain string) bool {
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// pathSegments splits the path of u into its non empty segments.
+func pathSegments(u *url.URL) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// setIfEmpty fills in a field that the generic scraping couldn't find.
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = strings.TrimSpace(value)
+	}
+}
+
+// setIfNotEmpty overrides a field with a value from a better source.
+func setIfNotEmpty(field *string, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		*field = value
+	}
+}
@@ -0,0 +1,242 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// fixtureServer answers the requests for the paths in fixtures with the
+// recorded responses in testdata, and with 404 for anything else. The
+// requests it got are recorded as well.
+func fixtureServer(t *testing.T, fixtures map[string]string) (*httptest.Server, *[]*url.URL) {
+	t.Helper()
+	requests := []*url.URL{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL)
+		fixture, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", fixture))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// fixturePage parses a recorded page as the one at rawURL.
+func fixturePage(t *testing.T, rawURL string, fixture string) *Page {
+	t.Helper()
+	page := &Page{URL: mustParse(t, rawURL)}
+	if fixture == "" {
+		return page
+	}
+	f, err := os.Open(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if page.Doc, err = goquery.NewDocumentFromReader(f); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func mustParse(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func enrich(t *testing.T, e Enricher, page *Page) *Metadata {
+	t.Helper()
+	if !e.Match(page.URL) {
+		t.Fatalf("%s doesn't match %s", e.Name(), page.URL)
+	}
+	md := &Metadata{URL: page.URL.String()}
+	if err := e.Enrich(context.Background(), testClient(t), page, md); err != nil {
+		t.Fatal(err)
+	}
+	return md
+}
+
+func TestGitHub(t *testing.T) {
+	server, requests := fixtureServer(t, map[string]string{"/repos/lukasmwerner/mark": "github_repo.json"})
+	github := &GitHub{APIBase: server.URL}
+
+	md := enrich(t, github, fixturePage(t, "https://github.com/lukasmwerner/mark.git", ""))
+	if md.Title != "lukasmwerner/mark" || md.Description != "A bookmark manager for the terminal" {
+		t.Errorf("got title %q and description %q", md.Title, md.Description)
+	}
+	if md.Stars != 128 || md.Author != "lukasmwerner" || md.SiteName != "GitHub" {
+		t.Errorf("got stars %d, author %q and site %q", md.Stars, md.Author, md.SiteName)
+	}
+	if !reflect.DeepEqual(md.Tags, []string{"bookmarks", "cli"}) {
+		t.Errorf("got tags %q", md.Tags)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("made %d requests", len(*requests))
+	}
+}
+
+func TestGitHubMatch(t *testing.T) {
+	github := &GitHub{}
+	for rawURL, want := range map[string]bool{
+		"https://github.com/lukasmwerner/mark":              true,
+		"https://github.com/lukasmwerner/mark/":             true,
+		"https://github.com/lukasmwerner/mark/issues/5":     false,
+		"https://github.com/lukasmwerner/mark/pull/7":       false,
+		"https://github.com/lukasmwerner/mark/blob/main/go": false,
+		"https://github.com/lukasmwerner":                   false,
+		"https://github.com/orgs/golang":                    false,
+		"https://github.com/topics/go":                      false,
+		"https://gitlab.com/lukasmwerner/mark":              false,
+	} {
+		if got := github.Match(mustParse(t, rawURL)); got != want {
+			t.Errorf("Match(%s) = %v, want %v", rawURL, got, want)
+		}
+	}
+}
+
+func TestOEmbed(t *testing.T) {
+	server, requests := fixtureServer(t, map[string]string{
+		"/vimeo":   "oembed_vimeo.json",
+		"/youtube": "oembed_youtube.json",
+	})
+	oembed := &OEmbed{Providers: []OEmbedProvider{
+		{Name: "YouTube", Domains: []string{"youtube.com"}, Endpoint: server.URL + "/youtube"},
+		{Name: "Vimeo", Domains: []string{"vimeo.com"}, Endpoint: server.URL + "/vimeo"},
+	}}
+
+	md := enrich(t, oembed, fixturePage(t, "https://vimeo.com/1234", ""))
+	if md.Title != "A short film" || md.Author != "Some Studio" || md.Description != "Filmed in one take." {
+		t.Errorf("got title %q, author %q and description %q", md.Title, md.Author, md.Description)
+	}
+	if md.Duration != 754*time.Second || md.Image != "https://i.vimeocdn.com/video/1.jpg" {
+		t.Errorf("got duration %s and image %q", md.Duration, md.Image)
+	}
+	if got := (*requests)[0].Query().Get("url"); got != "https://vimeo.com/1234" {
+		t.Errorf("asked for the oembed of %q", got)
+	}
+
+	// YouTube leaves the duration to the page
+	md = enrich(t, oembed, fixturePage(t, "https://www.youtube.com/watch?v=abc", "youtube_page.html"))
+	if md.Title != "Conference talk" || md.SiteName != "YouTube" {
+		t.Errorf("got title %q and site %q", md.Title, md.SiteName)
+	}
+	if md.Duration != time.Hour+4*time.Minute+13*time.Second {
+		t.Errorf("got duration %s", md.Duration)
+	}
+}
+
+func TestArXiv(t *testing.T) {
+	server, requests := fixtureServer(t, map[string]string{"/query": "arxiv_query.xml"})
+	arxiv := &ArXiv{APIBase: server.URL + "/query"}
+
+	md := enrich(t, arxiv, fixturePage(t, "https://arxiv.org/pdf/1706.03762v7.pdf", ""))
+	if md.Title != "Attention Is All You Need" {
+		t.Errorf("got title %q", md.Title)
+	}
+	if !strings.HasPrefix(md.Description, "The dominant sequence transduction models") {
+		t.Errorf("got description %q", md.Description)
+	}
+	if !reflect.DeepEqual(md.Authors, []string{"Ashish Vaswani", "Noam Shazeer"}) || md.Author != "Ashish Vaswani, Noam Shazeer" {
+		t.Errorf("got authors %q and author %q", md.Authors, md.Author)
+	}
+	if !reflect.DeepEqual(md.Keywords, []string{"cs.CL", "cs.LG"}) {
+		t.Errorf("got keywords %q", md.Keywords)
+	}
+	if !md.Published.Equal(time.Date(2017, 6, 12, 17, 57, 34, 0, time.UTC)) {
+		t.Errorf("got published %s", md.Published)
+	}
+	if got := (*requests)[0].Query().Get("id_list"); got != "1706.03762v7" {
+		t.Errorf("asked for paper %q", got)
+	}
+}
+
+func TestHackerNews(t *testing.T) {
+	server, _ := fixtureServer(t, map[string]string{"/item/8863.json": "hackernews_item.json"})
+	hn := &HackerNews{APIBase: server.URL}
+
+	md := enrich(t, hn, fixturePage(t, "https://news.ycombinator.com/item?id=8863", ""))
+	if md.LinkedURL != "http://www.getdropbox.com/u/2/screencast.html" {
+		t.Errorf("got linked url %q", md.LinkedURL)
+	}
+	if md.Title != "My YC app: Dropbox - Throw away your USB drive" || md.Author != "someone" {
+		t.Errorf("got title %q and author %q", md.Title, md.Author)
+	}
+	if md.Published.Unix() != 1175714200 {
+		t.Errorf("got published %s", md.Published)
+	}
+	if hn.Match(mustParse(t, "https://news.ycombinator.com/news")) {
+		t.Error("matched the front page")
+	}
+}
+
+func TestReddit(t *testing.T) {
+	server, requests := fixtureServer(t, map[string]string{
+		"/r/golang/comments/abc123/.json": "reddit_post.json",
+		"/r/golang/comments/def456/.json": "reddit_self_post.json",
+	})
+	reddit := &Reddit{Base: server.URL}
+
+	md := enrich(t, reddit, fixturePage(t, "https://old.reddit.com/r/golang/comments/abc123/an_interesting_article/", ""))
+	if md.LinkedURL != "https://example.com/article" || md.Title != "An interesting article" {
+		t.Errorf("got linked url %q and title %q", md.LinkedURL, md.Title)
+	}
+	if md.SiteName != "r/golang" || md.Author != "poster" {
+		t.Errorf("got site %q and author %q", md.SiteName, md.Author)
+	}
+	if got := (*requests)[0].Path; got != "/r/golang/comments/abc123/.json" {
+		t.Errorf("requested %s", got)
+	}
+
+	md = enrich(t, reddit, fixturePage(t, "https://www.reddit.com/r/golang/comments/def456/ask/", ""))
+	if md.LinkedURL != "" || md.Description != "I have thousands of them." {
+		t.Errorf("got linked url %q and description %q for a self post", md.LinkedURL, md.Description)
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	jsonld := &JSONLD{}
+
+	md := enrich(t, jsonld, fixturePage(t, "https://blog.example.com/posts/mark", "jsonld_article.html"))
+	if md.Title != "Writing a bookmark manager" || md.Description != "Notes on building mark." {
+		t.Errorf("got title %q and description %q", md.Title, md.Description)
+	}
+	if !reflect.DeepEqual(md.Authors, []string{"Ada", "Grace"}) {
+		t.Errorf("got authors %q", md.Authors)
+	}
+	if !reflect.DeepEqual(md.Keywords, []string{"go", "sqlite", "crdt"}) {
+		t.Errorf("got keywords %q", md.Keywords)
+	}
+	if md.Image != "https://blog.example.com/images/cover.png" || md.Language != "en" {
+		t.Errorf("got image %q and language %q", md.Image, md.Language)
+	}
+	if !md.Published.Equal(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("got published %s", md.Published)
+	}
+
+	// the html meta tags win over json-ld
+	md = &Metadata{Title: "From the meta tags"}
+	page := fixturePage(t, "https://blog.example.com/posts/mark", "jsonld_article.html")
+	if err := jsonld.Enrich(context.Background(), testClient(t), page, md); err != nil {
+		t.Fatal(err)
+	}
+	if md.Title != "From the meta tags" {
+		t.Errorf("json-ld replaced the title with %q", md.Title)
+	}
+}
@@ -0,0 +1,58 @@
+package scrape
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// GitHub uses the GitHub api for repository pages to get the description,
+// topics and star count.
+type GitHub struct {
+	APIBase string
+}
+
+func (g *GitHub) Name() string { return "github" }
+
+var githubReservedOwners = map[string]bool{
+	"orgs": true, "settings": true, "marketplace": true, "topics": true,
+	"explore": true, "sponsors": true, "features": true, "collections": true,
+}
+
+// Match only accepts the root of a repository, issues, pull requests and
+// files have their own title and description.
+func (g *GitHub) Match(u *url.URL) bool {
+	segments := pathSegments(u)
+	return strings.EqualFold(u.Hostname(), "github.com") &&
+		len(segments) == 2 && !githubReservedOwners[segments[0]]
+}
+
+type githubRepo struct {
+	FullName    string   `json:"full_name"`
+	Description string   `json:"description"`
+	Topics      []string `json:"topics"`
+	Stars       int      `json:"stargazers_count"`
+	Homepage    string   `json:"homepage"`
+	Owner       struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+func (g *GitHub) Enrich(ctx context.Context, c *Client, page *Page, md *Metadata) error {
+	segments := pathSegments(page.URL)
+	owner, repo := segments[0], strings.TrimSuffix(segments[1], ".git")
+
+	var r githubRepo
+	err := c.getJSON(ctx, g.APIBase+"/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(repo), &r)
+	if err != nil {
+		return err
+	}
+
+	setIfEmpty(&md.Title, r.FullName)
+	setIfNotEmpty(&md.Description, r.Description)
+	setIfNotEmpty(&md.Author, r.Owner.Login)
+	md.SiteName = "GitHub"
+	md.Tags = append(md.Tags, r.Topics...)
+	md.Stars = r.Stars
+	return nil
+}
@@ -0,0 +1,57 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MaxImageSize caps the size of downloaded favicons and preview images.
+const MaxImageSize = 2 << 20
+
+// Image is a downloaded favicon or preview image.
+type Image struct {
+	URL         string
+	ContentType string
+	Data        []byte
+}
+
+// FetchImage downloads the image at rawURL, returning an error if the
+// response isn't an image or is larger than MaxImageSize.
+func (c *Client) FetchImage(ctx context.Context, rawURL string) (*Image, error) {
+	if !Supports(rawURL) {
+		return nil, ErrUnsupported
+	}
+	resp, err := c.get(ctx, rawURL, "image/*")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImageSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, MaxImageSize)
+	}
+	if len(data) == 0 {
+		return nil, errors.New(rawURL + " is empty")
+	}
+
+	// servers often get the content type of icons wrong, so fall back to
+	// sniffing the data
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("%s is not an image", rawURL)
+	}
+
+	return &Image{URL: resp.Request.URL.String(), ContentType: contentType, Data: data}, nil
+}
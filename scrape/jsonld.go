@@ -0,0 +1,162 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// JSONLD reads schema.org Article data embedded in any page as json-ld,
+// filling in whatever the html meta tags left out.
+type JSONLD struct{}
+
+func (j *JSONLD) Name() string { return "json-ld" }
+
+func (j *JSONLD) Match(u *url.URL) bool { return true }
+
+var articleTypes = map[string]bool{
+	"Article": true, "NewsArticle": true, "BlogPosting": true, "TechArticle": true,
+	"ScholarlyArticle": true, "Report": true, "SocialMediaPosting": true,
+}
+
+type jsonldArticle struct {
+	Type          any               `json:"@type"`
+	Headline      string            `json:"headline"`
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	Author        json.RawMessage   `json:"author"`
+	DatePublished string            `json:"datePublished"`
+	Keywords      json.RawMessage   `json:"keywords"`
+	Image         json.RawMessage   `json:"image"`
+	InLanguage    string            `json:"inLanguage"`
+	Graph         []json.RawMessage `json:"@graph"`
+}
+
+func (j *JSONLD) Enrich(ctx context.Context, c *Client, page *Page, md *Metadata) error {
+	if page.Doc == nil {
+		return ErrSkipped
+	}
+
+	found := false
+	page.Doc.Find(`script[type='application/ld+json']`).Each(func(_ int, s *goquery.Selection) {
+		for _, article := range findArticles([]byte(s.Text())) {
+			applyArticle(article, page.URL, md)
+			found = true
+		}
+	})
+	if !found {
+		return ErrSkipped
+	}
+	return nil
+}
+
+// findArticles returns every article in a json-ld block, which may be a
+// single object, an array of objects or an object with a @graph.
+func findArticles(data []byte) []jsonldArticle {
+	var objects []json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		objects = []json.RawMessage{data}
+	}
+
+	articles := []jsonldArticle{}
+	for _, object := range objects {
+		var article jsonldArticle
+		if err := json.Unmarshal(object, &article); err != nil {
+			continue
+		}
+		if isArticle(article.Type) {
+			articles = append(articles, article)
+		}
+		for _, node := range article.Graph {
+			articles = append(articles, findArticles(node)...)
+		}
+	}
+	return articles
+}
+
+func isArticle(t any) bool {
+	switch t := t.(type) {
+	case string:
+		return articleTypes[t]
+	case []any:
+		for _, v := range t {
+			if isArticle(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func applyArticle(article jsonldArticle, pageURL *url.URL, md *Metadata) {
+	setIfEmpty(&md.Title, first(article.Headline, article.Name))
+	setIfEmpty(&md.Description, article.Description)
+	setIfEmpty(&md.Language, article.InLanguage)
+
+	authors := names(article.Author)
+	if len(md.Authors) == 0 {
+		md.Authors = authors
+	}
+	setIfEmpty(&md.Author, strings.Join(authors, ", "))
+
+	if md.Published.IsZero() {
+		md.Published = parseTime(article.DatePublished)
+	}
+
+	if len(md.Keywords) == 0 {
+		var keywords []string
+		var list string
+		if err := json.Unmarshal(article.Keywords, &keywords); err != nil {
+			json.Unmarshal(article.Keywords, &list)
+			keywords = strings.Split(list, ",")
+		}
+		md.Keywords = keywordList(keywords)
+	}
+
+	if md.Image == "" {
+		if images := urls(article.Image); len(images) > 0 {
+			md.Image = resolve(pageURL, images[0])
+		}
+	}
+}
+
+// names reads a schema.org Person or Organization, which may be a plain
+// string, an object with a name or an array of either.
+func names(data json.RawMessage) []string {
+	return stringsOrField(data, "name")
+}
+
+// urls reads a schema.org ImageObject in the same way as names.
+func urls(data json.RawMessage) []string {
+	return stringsOrField(data, "url")
+}
+
+func stringsOrField(data json.RawMessage, field string) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		items = []json.RawMessage{data}
+	}
+
+	values := []string{}
+	for _, item := range items {
+		var value string
+		if err := json.Unmarshal(item, &value); err == nil {
+			values = append(values, strings.TrimSpace(value))
+			continue
+		}
+		var object map[string]any
+		if err := json.Unmarshal(item, &object); err == nil {
+			if value, ok := object[field].(string); ok {
+				values = append(values, strings.TrimSpace(value))
+			}
+		}
+	}
+	return values
+}
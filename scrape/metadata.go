@@ -0,0 +1,167 @@
+package scrape
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Metadata is everything mark could find out about a page.
+type Metadata struct {
+	// URL is the address the page was fetched from after any redirects.
+	URL string
+	// Redirects are the urls that led to URL, starting with the one that was
+	// requested. It is empty if there were no redirects.
+	Redirects    []string
+	Title        string
+	Description  string
+	CanonicalURL string
+	SiteName     string
+	Image        string
+	Icon         string
+	Author       string
+	Published    time.Time
+	Keywords     []string
+	Language     string
+
+	// The fields below are only filled in by enrichers.
+
+	// Tags are suggested by the site itself, like a GitHub repo's topics.
+	Tags     []string
+	Authors  []string
+	Stars    int
+	Duration time.Duration
+	// LinkedURL is the article a discussion page (Hacker News, Reddit) links to.
+	LinkedURL string
+	// Warnings lists the enrichers that failed, the rest of the metadata is
+	// still usable.
+	Warnings []string
+}
+
+// Parse extracts the metadata from an html document, preferring OpenGraph
+// tags over the plain html ones. Relative links are resolved against pageURL.
+// Enrichers are not run, see Client.Fetch for that.
+func Parse(r io.Reader, pageURL *url.URL) (*Metadata, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocument(doc, pageURL), nil
+}
+
+func parseDocument(doc *goquery.Document, pageURL *url.URL) *Metadata {
+	md := &Metadata{URL: pageURL.String()}
+
+	md.Title = first(
+		meta(doc, "property", "og:title"),
+		meta(doc, "name", "twitter:title"),
+		doc.Find("head title").First().Text(),
+		doc.Find("title").First().Text(),
+	)
+	md.Description = first(
+		meta(doc, "property", "og:description"),
+		meta(doc, "name", "description"),
+		meta(doc, "name", "twitter:description"),
+	)
+	md.SiteName = meta(doc, "property", "og:site_name")
+	md.Author = first(
+		meta(doc, "name", "author"),
+		meta(doc, "property", "article:author"),
+	)
+	md.Language = first(
+		attr(doc.Find("html").First(), "lang"),
+		meta(doc, "http-equiv", "content-language"),
+		meta(doc, "property", "og:locale"),
+	)
+
+	if href, ok := doc.Find(`link[rel~='canonical']`).First().Attr("href"); ok {
+		md.CanonicalURL = resolve(pageURL, href)
+	}
+	if image := first(meta(doc, "property", "og:image"), meta(doc, "name", "twitter:image")); image != "" {
+		md.Image = resolve(pageURL, image)
+	}
+
+	icon := first(
+		attr(doc.Find(`link[rel~='icon']`).First(), "href"),
+		attr(doc.Find(`link[rel~='apple-touch-icon']`).First(), "href"),
+		"/favicon.ico",
+	)
+	md.Icon = resolve(pageURL, icon)
+
+	md.Published = parseTime(first(
+		meta(doc, "property", "article:published_time"),
+		meta(doc, "name", "date"),
+		meta(doc, "itemprop", "datePublished"),
+		attr(doc.Find("time[datetime]").First(), "datetime"),
+	))
+
+	keywords := strings.Split(meta(doc, "name", "keywords"), ",")
+	doc.Find(`meta[property='article:tag']`).Each(func(_ int, s *goquery.Selection) {
+		keywords = append(keywords, attr(s, "content"))
+	})
+	md.Keywords = keywordList(keywords)
+
+	return md
+}
+
+func meta(doc *goquery.Document, key string, value string) string {
+	return attr(doc.Find(`meta[`+key+`='`+value+`']`).First(), "content")
+}
+
+func attr(s *goquery.Selection, name string) string {
+	value, _ := s.Attr(name)
+	return strings.TrimSpace(value)
+}
+
+// first returns the first non empty value.
+func first(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+func parseTime(value string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func keywordList(keywords []string) []string {
+	list := []string{}
+	seen := map[string]bool{}
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" || seen[strings.ToLower(keyword)] {
+			continue
+		}
+		seen[strings.ToLower(keyword)] = true
+		list = append(list, keyword)
+	}
+	return list
+}
@@ -0,0 +1,101 @@
+package scrape
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// OEmbedProvider is a site that describes its pages through an oEmbed
+// endpoint.
+type OEmbedProvider struct {
+	Name     string
+	Domains  []string
+	Endpoint string
+}
+
+var DefaultOEmbedProviders = []OEmbedProvider{
+	{Name: "YouTube", Domains: []string{"youtube.com", "youtu.be"}, Endpoint: "https://www.youtube.com/oembed"},
+	{Name: "Vimeo", Domains: []string{"vimeo.com"}, Endpoint: "https://vimeo.com/api/oembed.json"},
+}
+
+// OEmbed gets the title, channel and duration of videos from the oEmbed
+// endpoint of their site.
+type OEmbed struct {
+	Providers []OEmbedProvider
+}
+
+func (o *OEmbed) Name() string { return "oembed" }
+
+func (o *OEmbed) provider(u *url.URL) (OEmbedProvider, bool) {
+	for _, provider := range o.Providers {
+		for _, domain := range provider.Domains {
+			if matchHost(u.Hostname(), domain) {
+				return provider, true
+			}
+		}
+	}
+	return OEmbedProvider{}, false
+}
+
+func (o *OEmbed) Match(u *url.URL) bool {
+	_, ok := o.provider(u)
+	return ok
+}
+
+type oembedResponse struct {
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	Description  string `json:"description"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	// Duration is in seconds, only some providers (Vimeo) include it.
+	Duration int `json:"duration"`
+}
+
+func (o *OEmbed) Enrich(ctx context.Context, c *Client, page *Page, md *Metadata) error {
+	provider, _ := o.provider(page.URL)
+
+	query := url.Values{"url": {page.URL.String()}, "format": {"json"}}
+	var r oembedResponse
+	err := c.getJSON(ctx, provider.Endpoint+"?"+query.Encode(), &r)
+	if err != nil {
+		return err
+	}
+
+	setIfNotEmpty(&md.Title, r.Title)
+	setIfEmpty(&md.Description, r.Description)
+	setIfNotEmpty(&md.Author, r.AuthorName)
+	setIfEmpty(&md.Image, r.ThumbnailURL)
+	md.SiteName = provider.Name
+	setIfNotEmpty(&md.SiteName, r.ProviderName)
+
+	if r.Duration > 0 {
+		md.Duration = time.Duration(r.Duration) * time.Second
+	} else if page.Doc != nil {
+		// YouTube leaves the duration out of oEmbed but has it in the page
+		content, _ := page.Doc.Find(`meta[itemprop='duration']`).First().Attr("content")
+		md.Duration = parseISODuration(content)
+	}
+	return nil
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?T?(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+
+// parseISODuration parses the ISO 8601 durations used by schema.org, such as
+// PT1H4M13S.
+func parseISODuration(value string) time.Duration {
+	match := isoDuration.FindStringSubmatch(value)
+	if match == nil {
+		return 0
+	}
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		n, _ := strconv.Atoi(match[i+1])
+		d += time.Duration(n) * unit
+	}
+	return d
+}
@@ -0,0 +1,193 @@
+// Package scrape fetches a page once and extracts the metadata mark uses to
+// fill in a bookmark: title, description, canonical url and so on.
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+type Client struct {
+	HTTP        *http.Client
+	UserAgent   string
+	MaxBodySize int64
+	// Enrichers default to every registered enricher.
+	Enrichers []Enricher
+}
+
+// NewClient makes a client that gives up on a request after timeout, none
+// when it is 0. proxy overrides the proxy from the HTTP_PROXY/HTTPS_PROXY
+// environment when it is set.
+func NewClient(timeout time.Duration, proxy string) (*Client, error) {
+	proxyFunc := http.ProxyFromEnvironment
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("unable to parse proxy: %s", proxy), err)
+		}
+		proxyFunc = http.ProxyURL(proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+
+	return &Client{
+		HTTP: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		Enrichers: Registered(),
+	}, nil
+}
+
+// StatusError is returned when the page responds with anything but 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded with %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// get makes a GET request with the client's user agent, returning a
+// *StatusError for non 200 responses.
+func (c *Client) get(ctx context.Context, rawURL string, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &StatusError{URL: rawURL, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// body limits the response body to MaxBodySize.
+func (c *Client) body(resp *http.Response) io.Reader {
+	if c.MaxBodySize <= 0 {
+		return resp.Body
+	}
+	return io.LimitReader(resp.Body, c.MaxBodySize)
+}
+
+// ErrUnsupported is returned by Fetch for urls that aren't web pages, such as
+// mailto: and file: links.
+var ErrUnsupported = errors.New("only http and https pages can be fetched")
+
+// Supports reports whether Fetch can get the metadata of rawURL.
+func Supports(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// Fetch downloads the page at rawURL and extracts its metadata, then lets
+// every matching enricher add what it knows about the page. Pages that aren't
+// html are not an error, they just have no metadata besides the url. If the
+// page itself can't be fetched the enrichers are still given a chance, as
+// most of them use a separate api.
+func (c *Client) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	if !Supports(rawURL) {
+		return nil, ErrUnsupported
+	}
+	pageURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	page, md, fetchErr := c.fetchPage(ctx, pageURL)
+	if fetchErr != nil {
+		page = &Page{URL: pageURL}
+		md = &Metadata{URL: rawURL}
+	}
+
+	enriched := false
+	for _, enricher := range c.Enrichers {
+		if !enricher.Match(page.URL) {
+			continue
+		}
+		err := enricher.Enrich(ctx, c, page, md)
+		if errors.Is(err, ErrSkipped) {
+			continue
+		}
+		if err != nil {
+			md.Warnings = append(md.Warnings, fmt.Sprintf("%s: %s", enricher.Name(), err))
+			continue
+		}
+		enriched = true
+	}
+
+	if fetchErr != nil && !enriched {
+		return nil, fetchErr
+	}
+	return md, nil
+}
+
+func (c *Client) fetchPage(ctx context.Context, pageURL *url.URL) (*Page, *Metadata, error) {
+	resp, err := c.get(ctx, pageURL.String(), "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	page := &Page{URL: resp.Request.URL}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return page, &Metadata{URL: page.URL.String(), Redirects: redirectChain(resp)}, nil
+	}
+
+	body, err := charset.NewReader(c.body(resp), contentType)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("unable to detect charset"), err)
+	}
+
+	page.Doc, err = goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	md := parseDocument(page.Doc, page.URL)
+	md.Redirects = redirectChain(resp)
+	if md.Language == "" {
+		md.Language = resp.Header.Get("Content-Language")
+	}
+	return page, md, nil
+}
+
+// redirectChain lists the urls that were redirected on the way to the final
+// response, oldest first.
+func redirectChain(resp *http.Response) []string {
+	chain := []string{}
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.Response.Request.URL.String()}, chain...)
+	}
+	return chain
+}
@@ -0,0 +1,159 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient is a client without enrichers, so only the page itself is
+// fetched.
+func testClient(t *testing.T) *Client {
+	t.Helper()
+	c, err := NewClient(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Enrichers = nil
+	return c
+}
+
+func TestFetchMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html lang="en"><head>
+<title>Plain title</title>
+<meta property="og:title" content="Open graph title">
+<meta name="description" content="A page">
+<link rel="canonical" href="/canonical">
+</head></html>`))
+	}))
+	defer server.Close()
+
+	md, err := testClient(t).Fetch(context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Title != "Open graph title" || md.Description != "A page" || md.Language != "en" {
+		t.Errorf("got title %q, description %q, language %q", md.Title, md.Description, md.Language)
+	}
+	if md.URL != server.URL+"/page" || md.CanonicalURL != server.URL+"/canonical" {
+		t.Errorf("got url %q and canonical url %q", md.URL, md.CanonicalURL)
+	}
+	if len(md.Redirects) != 1 || md.Redirects[0] != server.URL+"/old" {
+		t.Errorf("got redirects %q", md.Redirects)
+	}
+}
+
+func TestFetchCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"header", "text/html; charset=iso-8859-1", "<title>caf\xe9</title>"},
+		{"meta", "text/html", `<meta charset="iso-8859-1"><title>caf` + "\xe9" + `</title>`},
+		{"http-equiv", "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><title>caf` + "\xe9" + `</title>`},
+		{"utf-8", "text/html; charset=utf-8", "<title>café</title>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			md, err := testClient(t).Fetch(context.Background(), server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if md.Title != "café" {
+				t.Errorf("got title %q, want café", md.Title)
+			}
+		})
+	}
+}
+
+func TestFetchMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><!--" + strings.Repeat("x", 4096) + "--><title>Too far</title></head></html>"))
+	}))
+	defer server.Close()
+
+	c := testClient(t)
+	c.MaxBodySize = 1024
+	md, err := c.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Title != "" {
+		t.Errorf("read past the max body size, got title %q", md.Title)
+	}
+
+	c.MaxBodySize = 8192
+	md, err = c.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Title != "Too far" {
+		t.Errorf("got title %q, want Too far", md.Title)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	start := time.Now()
+	c, err := NewClient(100*time.Millisecond, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Enrichers = nil
+	_, err = c.Fetch(context.Background(), server.URL)
+	var timeout interface{ Timeout() bool }
+	if !errors.As(err, &timeout) || !timeout.Timeout() {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timed out after %s", elapsed)
+	}
+}
+
+func TestFetchStatusError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := testClient(t).Fetch(context.Background(), server.URL+"/missing")
+	var status *StatusError
+	if !errors.As(err, &status) {
+		t.Fatalf("got %v, want a *StatusError", err)
+	}
+	if status.StatusCode != http.StatusNotFound || status.URL != server.URL+"/missing" {
+		t.Errorf("got %+v", status)
+	}
+}
+
+func TestFetchUnsupported(t *testing.T) {
+	_, err := testClient(t).Fetch(context.Background(), "mailto:me@example.com")
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+}
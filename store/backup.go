@@ -0,0 +1,529 @@
+package store
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup is a backup archive in the backups directory of the store. It holds
+// a snapshot of data.db, the changes directory, config.toml and rules.toml.
+type Backup struct {
+	Path string
+	// Reason is why mark took the backup on its own, like "migration" or
+	// "bulk", and empty for backups taken with mark backup.
+	Reason  string
+	Created time.Time
+	Size    int64
+}
+
+// backupFiles are the files of the store kept in a backup besides data.db and
+// the changes directory.
+var backupFiles = []string{"config.toml", "rules.toml"}
+
+// restoredTables are the local tables a restore puts back along with the
+// bookmarks. The enrichment jobs and cached images describe this host's
+// queue and cache directory, which aren't in backups, so they are left be.
+var restoredTables = []string{"Imported_Items", "Feeds", "Feed_Items"}
+
+const backupTimeLayout = "20060102T150405.000"
+
+var errDryRun = errors.New("dry run")
+
+// BackupDir is where backups are kept.
+func (db *DB) BackupDir() string {
+	return path.Join(db.StoreLoc, "backups")
+}
+
+// WriteBackup writes a gzipped tar archive of the store to w. The database is
+// copied with VACUUM INTO, so the snapshot is consistent even while other
+// commands use the store.
+func WriteBackup(db *DB, w io.Writer) error {
+	// the changes of this host go in the backup up to now
+	err := syncronizeLocalChangesToDisk(db, path.Join(db.ChangesStoreLoc, db.Hostname))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "mark-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	snapshot := path.Join(tmp, "data.db")
+	if _, err := db.Exec(`VACUUM INTO ?;`, snapshot); err != nil {
+		return errors.Join(errors.New("unable to snapshot the database"), err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := addToBackup(tw, snapshot, "data.db"); err != nil {
+		return err
+	}
+	changes, err := os.ReadDir(db.ChangesStoreLoc)
+	if err != nil {
+		return err
+	}
+	for _, entry := range changes {
+		if entry.IsDir() {
+			continue
+		}
+		err := addToBackup(tw, path.Join(db.ChangesStoreLoc, entry.Name()), "changes/"+entry.Name())
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range backupFiles {
+		err := addToBackup(tw, path.Join(db.StoreLoc, name), name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addToBackup(tw *tar.Writer, file string, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// CreateBackup writes a backup into the backups directory, named after the
+// time it was taken and the reason mark took it on its own, if any.
+func CreateBackup(db *DB, reason string) (Backup, error) {
+	if err := EnsureDirExists(db.BackupDir()); err != nil {
+		return Backup{}, err
+	}
+	name := "mark-" + time.Now().Format(backupTimeLayout)
+	if reason != "" {
+		name += "-" + reason
+	}
+	file := path.Join(db.BackupDir(), name+".tar.gz")
+
+	tmp, err := os.CreateTemp(db.BackupDir(), ".mark-backup-*")
+	if err != nil {
+		return Backup{}, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := WriteBackup(db, tmp); err != nil {
+		tmp.Close()
+		return Backup{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Backup{}, err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return Backup{}, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return Backup{}, err
+	}
+	backup, _ := parseBackup(info)
+	backup.Path = file
+	return backup, nil
+}
+
+// AutoBackup takes a backup before mark changes many bookmarks at once, and
+// removes the oldest automatic backups beyond the ones the config keeps. No
+// backup is taken when they are turned off or the store has no bookmarks, the
+// returned backup has no Path then.
+func AutoBackup(db *DB, reason string) (Backup, error) {
+	return autoBackup(db, reason, "")
+}
+
+// AutoBackupBeforeRestore is AutoBackup before restoring archive. archive
+// is never removed with the oldest automatic backups, so it is still there
+// to be restored when it is one of them.
+func AutoBackupBeforeRestore(db *DB, archive string) (Backup, error) {
+	return autoBackup(db, "restore", archive)
+}
+
+func autoBackup(db *DB, reason string, exclude string) (Backup, error) {
+	if !db.Config.Backup.Automatic {
+		return Backup{}, nil
+	}
+	var hasBookmarks bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM Bookmarks);`).Scan(&hasBookmarks)
+	if err != nil || !hasBookmarks {
+		return Backup{}, err
+	}
+
+	backup, err := CreateBackup(db, reason)
+	if err != nil {
+		return Backup{}, errors.Join(errors.New("unable to back up the store, automatic backups can be turned off in the [backup] section of config.toml"), err)
+	}
+	return backup, pruneBackups(db, db.Config.Backup.Keep, exclude)
+}
+
+// backupBeforeMigrating takes an automatic backup when migrations are about
+// to change the database.
+func backupBeforeMigrating(db *DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version;`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(Migrations) {
+		return nil
+	}
+	_, err := AutoBackup(db, "migration")
+	return err
+}
+
+// pruneBackups removes the automatic backups beyond the newest keep, except
+// for the file exclude. With keep 0 every backup is kept.
+func pruneBackups(db *DB, keep int, exclude string) error {
+	if keep <= 0 {
+		return nil
+	}
+	backups, err := ListBackups(db)
+	if err != nil {
+		return err
+	}
+	automatic := []Backup{}
+	for _, backup := range backups {
+		// the ones taken with mark backup have no reason and are never removed
+		if backup.Reason != "" {
+			automatic = append(automatic, backup)
+		}
+	}
+	if len(automatic) <= keep {
+		return nil
+	}
+
+	excluded, _ := os.Stat(exclude)
+	for _, backup := range automatic[keep:] {
+		if excluded != nil {
+			if info, err := os.Stat(backup.Path); err == nil && os.SameFile(info, excluded) {
+				continue
+			}
+		}
+		if err := os.Remove(backup.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListBackups returns the backups in the backups directory, newest first.
+func ListBackups(db *DB) ([]Backup, error) {
+	backups := []Backup{}
+	entries, err := os.ReadDir(db.BackupDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return backups, nil
+	}
+	if err != nil {
+		return backups, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return backups, err
+		}
+		if backup, ok := parseBackup(info); ok {
+			backup.Path = path.Join(db.BackupDir(), entry.Name())
+			backups = append(backups, backup)
+		}
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// parseBackup reads the time and reason of a backup from its file name.
+func parseBackup(info os.FileInfo) (Backup, bool) {
+	name, found := strings.CutSuffix(info.Name(), ".tar.gz")
+	if !found || info.IsDir() {
+		return Backup{}, false
+	}
+	name, found = strings.CutPrefix(name, "mark-")
+	if !found {
+		return Backup{}, false
+	}
+	stamp, reason, _ := strings.Cut(name, "-")
+	created, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+	if err != nil {
+		return Backup{}, false
+	}
+	return Backup{Reason: reason, Created: created, Size: info.Size()}, true
+}
+
+// RestoreOptions change what RestoreBackup does.
+type RestoreOptions struct {
+	// Config also puts back config.toml and rules.toml.
+	Config bool
+	// DryRun only counts what would change.
+	DryRun bool
+}
+
+// RestoreResult counts how the bookmarks changed.
+type RestoreResult struct {
+	Added     int
+	Updated   int
+	Deleted   int
+	Unchanged int
+}
+
+// RestoreBackup makes the store hold the bookmarks of a backup again.
+//
+// data.db isn't replaced by the snapshot: it keeps its cr-sqlite site id, and
+// the restored bookmarks are written as new changes of this host. A replaced
+// database would go back to older versions the other hosts have already
+// seen, and they would keep their newer bookmarks over the restored ones, or
+// share a site id with this host when the backup came from there. Bookmarks
+// that aren't in the backup are deleted the same way.
+func RestoreBackup(db *DB, archive string, opts RestoreOptions) (RestoreResult, error) {
+	result := RestoreResult{}
+
+	tmp, err := os.MkdirTemp("", "mark-restore-*")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := extractBackup(archive, tmp); err != nil {
+		return result, errors.Join(errors.New("unable to read the backup "+archive), err)
+	}
+
+	bookmarks, tables, err := readSnapshot(db, path.Join(tmp, "data.db"))
+	if err != nil {
+		return result, errors.Join(errors.New("unable to read the database in "+archive), err)
+	}
+
+	err = Transaction(db, func(tx *Tx) error {
+		live, err := ListBookmarks(tx)
+		if err != nil {
+			return err
+		}
+		restored := map[BookmarkId]bool{}
+		for _, b := range bookmarks {
+			restored[b.Id] = true
+		}
+		current := map[BookmarkId]Bookmark{}
+		for _, b := range live {
+			if restored[b.Id] {
+				current[b.Id] = b
+				continue
+			}
+			if err := DeleteBookmark(tx, b.Id); err != nil {
+				return errors.Join(fmt.Errorf("unable to delete bookmark %d", b.Id), err)
+			}
+			result.Deleted++
+		}
+
+		for _, b := range bookmarks {
+			existing, found := current[b.Id]
+			// the canonical url is computed again with the current rules
+			existing.CanonicalUrl, b.CanonicalUrl = "", ""
+			switch {
+			case found && reflect.DeepEqual(existing, b):
+				result.Unchanged++
+				continue
+			case found:
+				result.Updated++
+			default:
+				result.Added++
+			}
+			if err := RestoreBookmark(tx, b); err != nil {
+				return errors.Join(fmt.Errorf("unable to restore bookmark %d %s", b.Id, b.Url), err)
+			}
+		}
+
+		for _, table := range restoredTables {
+			if err := replaceRows(tx, table, tables[table]); err != nil {
+				return errors.Join(errors.New("unable to restore "+table), err)
+			}
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return result, nil
+	}
+	if err != nil || !opts.Config {
+		return result, err
+	}
+
+	for _, name := range backupFiles {
+		data, err := os.ReadFile(path.Join(tmp, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		if err := writeFileAtomic(path.Join(db.StoreLoc, name), data); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// extractBackup writes data.db and the files of the store in a backup to dir.
+// The changes directory isn't needed for a restore: the ones of the other
+// hosts are older than what they have synced since.
+func extractBackup(archive string, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	wanted := map[string]bool{"data.db": true}
+	for _, name := range backupFiles {
+		wanted[name] = true
+	}
+	foundDB := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !wanted[header.Name] || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		out, err := os.Create(path.Join(dir, header.Name))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		foundDB = foundDB || header.Name == "data.db"
+	}
+	if !foundDB {
+		return errors.New("there is no data.db in it")
+	}
+	return nil
+}
+
+// readSnapshot reads the bookmarks and local tables of a database from a
+// backup, migrating it first in case it is from an older version of mark.
+func readSnapshot(db *DB, file string) ([]Bookmark, map[string][][]any, error) {
+	sqlDB := sql.OpenDB(fileConnector{driver: db.Driver(), file: file})
+	// the snapshot is thrown away, so its changes are never synced
+	snapshot := &DB{DB: sqlDB, Config: db.Config}
+	defer func() {
+		snapshot.Exec(`select crsql_finalize();`)
+		sqlDB.Close()
+	}()
+
+	if err := EnsureTables(snapshot, Tables...); err != nil {
+		return nil, nil, err
+	}
+	if _, err := snapshot.Exec("select crsql_as_crr('Bookmarks');"); err != nil {
+		return nil, nil, err
+	}
+	if err := Migrate(snapshot); err != nil {
+		return nil, nil, err
+	}
+
+	bookmarks, err := ListBookmarks(snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	tables := map[string][][]any{}
+	for _, table := range restoredTables {
+		if tables[table], err = readRows(snapshot, table); err != nil {
+			return nil, nil, err
+		}
+	}
+	return bookmarks, tables, nil
+}
+
+// fileConnector opens a database file with the driver of the store, so it
+// has the same extensions loaded.
+type fileConnector struct {
+	driver driver.Driver
+	file   string
+}
+
+func (c fileConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.file) }
+func (c fileConnector) Driver() driver.Driver                        { return c.driver }
+
+func readRows(db Querier, table string) ([][]any, error) {
+	rows, err := db.Query(`SELECT * FROM ` + table + `;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := [][]any{}
+	for rows.Next() {
+		row := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		values = append(values, row)
+	}
+	return values, rows.Err()
+}
+
+func replaceRows(db Querier, table string, rows [][]any) error {
+	if _, err := db.Exec(`DELETE FROM ` + table + `;`); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(row)), ", ")
+		if _, err := db.Exec(`INSERT INTO `+table+` VALUES (`+placeholders+`);`, row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lukasmwerner/mark/store"
+	"github.com/lukasmwerner/mark/store/storetest"
+)
+
+// writeArchive backs up db into a file outside of its backups directory.
+func writeArchive(t *testing.T, db *store.DB) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := store.WriteBackup(db, f); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func insert(t *testing.T, db *store.DB, bookmark store.Bookmark) store.BookmarkId {
+	t.Helper()
+	id, err := store.InsertBookmark(db, bookmark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func urls(t *testing.T, db *store.DB) []string {
+	t.Helper()
+	bookmarks, err := store.ListBookmarks(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{}
+	for _, b := range bookmarks {
+		urls = append(urls, b.Url)
+	}
+	return urls
+}
+
+func TestRestoreBackupIntoFreshStore(t *testing.T) {
+	backedUp := storetest.Open(t)
+	insert(t, backedUp, store.Bookmark{Url: "https://example.com/a", Title: "A", Tags: []string{"go"}})
+	insert(t, backedUp, store.Bookmark{Url: "https://example.com/b", Title: "B"})
+	if err := store.AddFeed(backedUp, store.Feed{Url: "https://example.com/feed.xml"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordImportedItems(backedUp, "firefox", []string{"guid-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(backedUp.StoreLoc, "rules.toml"), []byte("# restored\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive := writeArchive(t, backedUp)
+
+	db := storetest.Open(t)
+	insert(t, db, store.Bookmark{Url: "https://example.com/c", Title: "C"})
+
+	preview, err := store.RestoreBackup(db, archive, store.RestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the bookmark of the fresh store has the id of the first one backed up
+	if preview != (store.RestoreResult{Added: 1, Updated: 1}) {
+		t.Errorf("dry run: got %+v", preview)
+	}
+	if got := urls(t, db); !reflect.DeepEqual(got, []string{"https://example.com/c"}) {
+		t.Errorf("the dry run changed the bookmarks to %q", got)
+	}
+
+	result, err := store.RestoreBackup(db, archive, store.RestoreOptions{Config: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != preview {
+		t.Errorf("got %+v, the dry run counted %+v", result, preview)
+	}
+	if got := urls(t, db); !reflect.DeepEqual(got, []string{"https://example.com/a", "https://example.com/b"}) {
+		t.Errorf("got bookmarks %q", got)
+	}
+	a, err := store.FindBookmarkByURL(db, "https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "A" || !reflect.DeepEqual(a.Tags, []string{"go"}) {
+		t.Errorf("got title %q and tags %q", a.Title, a.Tags)
+	}
+
+	if _, err := store.GetFeed(db, "https://example.com/feed.xml"); err != nil {
+		t.Errorf("the feed wasn't restored: %v", err)
+	}
+	imported, err := store.ImportedItems(db, "firefox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imported["guid-a"] {
+		t.Errorf("got imported items %v", imported)
+	}
+	rules, err := os.ReadFile(filepath.Join(db.StoreLoc, "rules.toml"))
+	if err != nil || string(rules) != "# restored\n" {
+		t.Errorf("got rules.toml %q, %v", rules, err)
+	}
+
+	// restoring again changes nothing
+	result, err = store.RestoreBackup(db, archive, store.RestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (store.RestoreResult{Unchanged: 2}) {
+		t.Errorf("second restore: got %+v", result)
+	}
+}
+
+func TestRestoreOnlyAutomaticBackup(t *testing.T) {
+	db := storetest.Open(t)
+	db.Config.Backup = store.BackupOptions{Automatic: true, Keep: 1}
+	insert(t, db, store.Bookmark{Url: "https://example.com/a"})
+	backup, err := store.AutoBackup(db, "bulk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert(t, db, store.Bookmark{Url: "https://example.com/b"})
+
+	// like mark restore latest, which takes a backup of its own first
+	time.Sleep(5 * time.Millisecond)
+	if _, err := store.AutoBackupBeforeRestore(db, backup.Path); err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.RestoreBackup(db, backup.Path, store.RestoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (store.RestoreResult{Deleted: 1, Unchanged: 1}) {
+		t.Errorf("got %+v", result)
+	}
+	if got := urls(t, db); !reflect.DeepEqual(got, []string{"https://example.com/a"}) {
+		t.Errorf("got bookmarks %q", got)
+	}
+
+	// the next automatic backup prunes it as usual
+	time.Sleep(5 * time.Millisecond)
+	latest, err := store.AutoBackup(db, "retag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, err := store.ListBackups(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Path != latest.Path {
+		t.Errorf("kept %+v, want only %s", backups, latest.Path)
+	}
+}
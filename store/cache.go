@@ -0,0 +1,182 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// The kinds of images cached for a bookmark.
+const (
+	ImageIcon    = "icon"
+	ImagePreview = "preview"
+)
+
+// CachedImage is a favicon or preview image of a bookmark. The file is named
+// after the sha256 of its contents, so bookmarks sharing an icon share the
+// file. The cache isn't synced, every host downloads its own images.
+type CachedImage struct {
+	BookmarkId  BookmarkId
+	Kind        string
+	Hash        string
+	SourceUrl   string
+	ContentType string
+	FetchedAt   time.Time
+}
+
+// CacheDir is where the cached images are kept.
+func (db *DB) CacheDir() string {
+	return path.Join(db.StoreLoc, "cache")
+}
+
+// ImagePath is the file holding a cached image.
+func (db *DB) ImagePath(image CachedImage) string {
+	return path.Join(db.CacheDir(), image.Hash[:2], image.Hash)
+}
+
+// SaveImage stores the image data in the cache and records it as the kind
+// of image for the bookmark, replacing the previous one.
+func SaveImage(db Querier, id BookmarkId, kind string, sourceURL string, contentType string, data []byte) (CachedImage, error) {
+	sum := sha256.Sum256(data)
+	image := CachedImage{
+		BookmarkId:  id,
+		Kind:        kind,
+		Hash:        hex.EncodeToString(sum[:]),
+		SourceUrl:   sourceURL,
+		ContentType: contentType,
+		FetchedAt:   time.Now(),
+	}
+
+	file := db.store().ImagePath(image)
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomic(file, data); err != nil {
+			return image, errors.Join(errors.New("unable to write to the cache"), err)
+		}
+	}
+
+	_, err := db.Exec(`INSERT INTO Cached_Images (bookmark_id, kind, hash, source_url, content_type, fetched_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (bookmark_id, kind) DO UPDATE SET hash = excluded.hash, source_url = excluded.source_url,
+		content_type = excluded.content_type, fetched_at = excluded.fetched_at;`,
+		image.BookmarkId, image.Kind, image.Hash, image.SourceUrl, image.ContentType, image.FetchedAt.Unix())
+	return image, err
+}
+
+func writeFileAtomic(file string, data []byte) error {
+	if err := os.MkdirAll(path.Dir(file), 0775); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(path.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// GetImage returns the kind of image cached for a bookmark, or ErrNotFound.
+func GetImage(db Querier, id BookmarkId, kind string) (CachedImage, error) {
+	image := CachedImage{BookmarkId: id, Kind: kind}
+	var fetchedAt sql.NullInt64
+	err := db.QueryRow(`SELECT hash, COALESCE(source_url, ''), COALESCE(content_type, ''), fetched_at FROM Cached_Images
+	WHERE bookmark_id = ? AND kind = ?;`, id, kind).Scan(&image.Hash, &image.SourceUrl, &image.ContentType, &fetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return image, ErrNotFound
+	}
+	image.FetchedAt = fromUnix(fetchedAt)
+	return image, err
+}
+
+// ReadImage returns the contents of a cached image.
+func ReadImage(db Querier, id BookmarkId, kind string) (CachedImage, []byte, error) {
+	image, err := GetImage(db, id, kind)
+	if err != nil {
+		return image, nil, err
+	}
+	data, err := os.ReadFile(db.store().ImagePath(image))
+	if errors.Is(err, os.ErrNotExist) {
+		return image, nil, ErrNotFound
+	}
+	return image, data, err
+}
+
+func forgetImages(db Querier, id BookmarkId) error {
+	_, err := db.Exec(`DELETE FROM Cached_Images WHERE bookmark_id = ?;`, id)
+	return err
+}
+
+// PruneStats is what PruneCache removed, or would remove on a dry run.
+type PruneStats struct {
+	Records int
+	Files   int
+	Bytes   int64
+}
+
+// PruneCache forgets the images of bookmarks that no longer exist, for
+// example because they were deleted on another host, and removes the files no
+// bookmark uses anymore. With dryRun nothing is removed.
+func PruneCache(db *DB, dryRun bool) (PruneStats, error) {
+	var stats PruneStats
+
+	orphans := `FROM Cached_Images WHERE bookmark_id NOT IN (SELECT id FROM Bookmarks)`
+	err := db.QueryRow(`SELECT COUNT(*) ` + orphans + `;`).Scan(&stats.Records)
+	if err != nil {
+		return stats, err
+	}
+	if !dryRun {
+		if _, err := db.Exec(`DELETE ` + orphans + `;`); err != nil {
+			return stats, err
+		}
+	}
+
+	rows, err := db.Query(`SELECT DISTINCT hash FROM Cached_Images WHERE bookmark_id IN (SELECT id FROM Bookmarks);`)
+	if err != nil {
+		return stats, err
+	}
+	used := map[string]bool{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		used[hash] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	err = filepath.WalkDir(db.CacheDir(), func(file string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil || entry.IsDir() || used[entry.Name()] {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		stats.Files++
+		stats.Bytes += info.Size()
+		if dryRun {
+			return nil
+		}
+		return os.Remove(file)
+	})
+	return stats, err
+}
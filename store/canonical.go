@@ -0,0 +1,115 @@
+package store
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// URLRules control how urls are canonicalized before being compared for
+// duplicates. They can be changed in the [canonical] section of config.toml.
+type URLRules struct {
+	// StripParams are query parameters removed from http(s) urls, they may
+	// be glob patterns such as utm_*.
+	StripParams []string `toml:"strip_params"`
+	// MergeHTTP treats http and https as the same page.
+	MergeHTTP bool `toml:"merge_http"`
+	// StripWWW treats www.example.com and example.com as the same host.
+	StripWWW bool `toml:"strip_www"`
+	// KeepFragment keeps the #fragment, for sites that use it for routing.
+	KeepFragment bool `toml:"keep_fragment"`
+	// KeepTrailingSlash keeps trailing slashes on the path.
+	KeepTrailingSlash bool `toml:"keep_trailing_slash"`
+}
+
+var DefaultURLRules = URLRules{
+	StripParams: []string{
+		"utm_*",
+		"fbclid",
+		"gclid",
+		"dclid",
+		"msclkid",
+		"yclid",
+		"igshid",
+		"mc_cid",
+		"mc_eid",
+		"_hsenc",
+		"_hsmi",
+		"ref_src",
+	},
+	MergeHTTP: true,
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// CanonicalURL returns the form of raw used to detect duplicate bookmarks:
+// the scheme and host are lowercased, default ports, tracking parameters and
+// fragments are removed and the remaining query parameters are sorted.
+func CanonicalURL(raw string, rules URLRules) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if _, ok := defaultPorts[u.Scheme]; !ok {
+		return u.String(), nil
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if rules.StripWWW {
+		host = strings.TrimPrefix(host, "www.")
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if rules.MergeHTTP {
+		u.Scheme = "https"
+	}
+
+	if !rules.KeepFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	if !rules.KeepTrailingSlash {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+	} else if u.Path == "" {
+		u.Path = "/"
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for param := range query {
+			if matchesAny(strings.ToLower(param), rules.StripParams) {
+				query.Del(param)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	defaults := store.DefaultURLRules
+	withRules := func(change func(*store.URLRules)) store.URLRules {
+		rules := defaults
+		change(&rules)
+		return rules
+	}
+
+	tests := []struct {
+		name  string
+		raw   string
+		rules store.URLRules
+		want  string
+	}{
+		{"scheme and host case", "HTTPS://Example.COM/Path", defaults, "https://example.com/Path"},
+		{"trailing dot", "https://example.com./a", defaults, "https://example.com/a"},
+		{"surrounding space", "  https://example.com/a  ", defaults, "https://example.com/a"},
+		{"http merged", "http://example.com/a", defaults, "https://example.com/a"},
+		{"http kept", "http://example.com/a", withRules(func(r *store.URLRules) { r.MergeHTTP = false }), "http://example.com/a"},
+		{"www kept", "https://www.example.com/a", defaults, "https://www.example.com/a"},
+		{"www stripped", "https://www.example.com/a", withRules(func(r *store.URLRules) { r.StripWWW = true }), "https://example.com/a"},
+		{"default https port", "https://example.com:443/a", defaults, "https://example.com/a"},
+		{"default http port", "http://example.com:80/a", withRules(func(r *store.URLRules) { r.MergeHTTP = false }), "http://example.com/a"},
+		{"other port", "https://example.com:8443/a", defaults, "https://example.com:8443/a"},
+		{"ipv6", "https://[::1]:443/a", defaults, "https://[::1]/a"},
+		{"trailing slash", "https://example.com/a/", defaults, "https://example.com/a"},
+		{"root slash", "https://example.com/", defaults, "https://example.com"},
+		{"trailing slash kept", "https://example.com/a/", withRules(func(r *store.URLRules) { r.KeepTrailingSlash = true }), "https://example.com/a/"},
+		{"root slash added", "https://example.com", withRules(func(r *store.URLRules) { r.KeepTrailingSlash = true }), "https://example.com/"},
+		{"utm params", "https://example.com/a?utm_source=x&UTM_Medium=y&id=1", defaults, "https://example.com/a?id=1"},
+		{"tracking params", "https://example.com/a?fbclid=1&gclid=2&ref_src=3", defaults, "https://example.com/a"},
+		{"own strip params", "https://example.com/a?session=1&id=2", withRules(func(r *store.URLRules) { r.StripParams = []string{"sess*"} }), "https://example.com/a?id=2"},
+		{"no strip params", "https://example.com/a?utm_source=x", withRules(func(r *store.URLRules) { r.StripParams = nil }), "https://example.com/a?utm_source=x"},
+		{"sorted query", "https://example.com/a?b=2&a=1&c=3", defaults, "https://example.com/a?a=1&b=2&c=3"},
+		{"empty query", "https://example.com/a?", defaults, "https://example.com/a"},
+		{"fragment", "https://example.com/a#section", defaults, "https://example.com/a"},
+		{"fragment kept", "https://example.com/#/route", withRules(func(r *store.URLRules) { r.KeepFragment = true }), "https://example.com#/route"},
+		{"fragment and slash kept", "https://example.com/#/route", withRules(func(r *store.URLRules) { r.KeepFragment, r.KeepTrailingSlash = true, true }), "https://example.com/#/route"},
+		{"other schemes", "MAILTO:Me@Example.com", defaults, "mailto:Me@Example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := store.CanonicalURL(test.raw, test.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("CanonicalURL(%q) = %q, want %q", test.raw, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalURLInvalid(t *testing.T) {
+	if _, err := store.CanonicalURL("https://exa mple.com/%zz", store.DefaultURLRules); err == nil {
+		t.Error("canonicalized an invalid url")
+	}
+}
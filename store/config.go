@@ -0,0 +1,76 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds the user's settings from config.toml in the store location.
+// Anything left out of the file keeps its default value.
+type Config struct {
+	Canonical URLRules      `toml:"canonical"`
+	Scrape    ScrapeOptions `toml:"scrape"`
+	Cache     CacheOptions  `toml:"cache"`
+	Backup    BackupOptions `toml:"backup"`
+}
+
+// ScrapeOptions configure the http client pages are fetched with, see
+// scrape.NewClient.
+type ScrapeOptions struct {
+	Timeout     time.Duration `toml:"timeout"`
+	UserAgent   string        `toml:"user_agent"`
+	MaxBodySize int64         `toml:"max_body_size"`
+	// Proxy overrides the proxy from the HTTP_PROXY/HTTPS_PROXY environment.
+	Proxy string `toml:"proxy"`
+}
+
+var DefaultScrapeOptions = ScrapeOptions{
+	Timeout:     10 * time.Second,
+	UserAgent:   "mark/1.0 (+https://github.com/lukasmwerner/mark)",
+	MaxBodySize: 5 << 20,
+}
+
+// CacheOptions choose which images are downloaded into the cache when a
+// bookmark's metadata is fetched.
+type CacheOptions struct {
+	Icons  bool `toml:"icons"`
+	Images bool `toml:"images"`
+}
+
+// BackupOptions control the backups mark takes on its own before migrating
+// the database and before changing many bookmarks at once.
+type BackupOptions struct {
+	Automatic bool `toml:"automatic"`
+	// Keep is how many automatic backups are kept, the oldest are removed.
+	// 0 keeps all of them. Backups taken with mark backup are never removed.
+	Keep int `toml:"keep"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Canonical: DefaultURLRules,
+		Scrape:    DefaultScrapeOptions,
+		Cache:     CacheOptions{Icons: true, Images: true},
+		Backup:    BackupOptions{Automatic: true, Keep: 10},
+	}
+}
+
+// LoadConfig reads config.toml from the store location, falling back to the
+// defaults when there is no config file.
+func LoadConfig(storeLoc string) (Config, error) {
+	config := DefaultConfig()
+
+	_, err := toml.DecodeFile(path.Join(storeLoc, "config.toml"), &config)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, errors.Join(errors.New("unable to read config.toml"), err)
+	}
+
+	return config, nil
+}
@@ -3,14 +3,32 @@ package store
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 )
 
+var ErrNotFound = errors.New("bookmark not found")
+var ErrDuplicate = errors.New("bookmark already exists")
+
+// DuplicateError is returned when a bookmark would have the same canonical url
+// as an existing bookmark. It matches ErrDuplicate with errors.Is.
+type DuplicateError struct {
+	Existing Bookmark
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("bookmark already exists: %d %s", e.Existing.Id, e.Existing.Url)
+}
+
+func (e *DuplicateError) Is(target error) bool { return target == ErrDuplicate }
+
 type requirement struct {
 	name       string
 	definition string
@@ -60,8 +78,76 @@ BEGIN
     VALUES (new.id, new.url, new.title, new.description, new.tags);
 END;`,
 	},
+	{
+		// Jobs are local to this host and not a crr, so only the host that
+		// added a bookmark fetches its metadata.
+		name: "Enrichment_Jobs",
+		definition: `CREATE TABLE IF NOT EXISTS Enrichment_Jobs (
+    bookmark_id INTEGER PRIMARY KEY NOT NULL,
+    primary_url TEXT NOT NULL DEFAULT 'canonical',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt INTEGER NOT NULL,
+    last_error TEXT
+);`,
+	},
+	{
+		// The favicons and preview images in the cache directory, local to
+		// this host like the files themselves.
+		name: "Cached_Images",
+		definition: `CREATE TABLE IF NOT EXISTS Cached_Images (
+    bookmark_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    source_url TEXT,
+    content_type TEXT,
+    fetched_at INTEGER,
+    PRIMARY KEY (bookmark_id, kind)
+);`,
+	},
+	{
+		// The ids of the bookmarks imported from each browser profile, so
+		// incremental imports leave them out. Local since the profiles
+		// being imported are too.
+		name: "Imported_Items",
+		definition: `CREATE TABLE IF NOT EXISTS Imported_Items (
+    source TEXT NOT NULL,
+    item_id TEXT NOT NULL,
+    imported_at INTEGER,
+    PRIMARY KEY (source, item_id)
+);`,
+	},
+	{
+		// Feed subscriptions are local so only one host polls each feed,
+		// the bookmarks they add sync like any other.
+		name: "Feeds",
+		definition: `CREATE TABLE IF NOT EXISTS Feeds (
+    url TEXT PRIMARY KEY NOT NULL,
+    title TEXT,
+    tags TEXT,
+    filter TEXT,
+    added_at INTEGER,
+    polled_at INTEGER,
+    etag TEXT,
+    last_modified TEXT,
+    last_error TEXT
+);`,
+	},
+	{
+		// The items of every feed seen so far, added as bookmarks or not.
+		name: "Feed_Items",
+		definition: `CREATE TABLE IF NOT EXISTS Feed_Items (
+    feed_url TEXT NOT NULL,
+    item_id TEXT NOT NULL,
+    seen_at INTEGER,
+    PRIMARY KEY (feed_url, item_id)
+);`,
+	},
 }
 
+// registerDriver makes sure the driver is registered once, sql.Register
+// panics when a name is registered again.
+var registerDriver sync.Once
+
 func Open() (*DB, error) {
 	markStoreLocation := os.Getenv("MARK_STORE_LOCATION")
 	if markStoreLocation == "" {
@@ -80,8 +166,10 @@ func Open() (*DB, error) {
 		return nil, errors.Join(errors.New("unable to make mark store changes location in: "+markStoreLocation), err)
 	}
 
-	sql.Register("cr-sqlite", &sqlite3.SQLiteDriver{
-		Extensions: []string{"crsqlite"},
+	registerDriver.Do(func() {
+		sql.Register("cr-sqlite", &sqlite3.SQLiteDriver{
+			Extensions: []string{"crsqlite"},
+		})
 	})
 
 	sqlDB, err := sql.Open("cr-sqlite", path.Join(markStoreLocation, "data.db"))
@@ -94,11 +182,17 @@ func Open() (*DB, error) {
 		return nil, err
 	}
 
+	config, err := LoadConfig(markStoreLocation)
+	if err != nil {
+		return nil, err
+	}
+
 	db := &DB{
 		DB:              sqlDB,
 		StoreLoc:        markStoreLocation,
 		ChangesStoreLoc: changesPath,
 		Hostname:        hostname,
+		Config:          config,
 	}
 
 	err = EnsureTables(db, Tables...)
@@ -111,6 +205,16 @@ func Open() (*DB, error) {
 		return nil, errors.Join(errors.New("unable to setup crdts"), err)
 	}
 
+	err = backupBeforeMigrating(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Migrate(db)
+	if err != nil {
+		return nil, errors.Join(errors.New("unable to migrate database"), err)
+	}
+
 	err = syncronizeFromHostsToDB(db, hostname, changesPath)
 	if err != nil {
 		return nil, errors.Join(errors.New("unable to sync fs -> db"), err)
@@ -125,6 +229,7 @@ type DB struct {
 	StoreLoc        string
 	ChangesStoreLoc string
 	Hostname        string
+	Config          Config
 }
 
 func (db *DB) Close() error {
@@ -141,6 +246,12 @@ func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
+// Refresh applies the changes other hosts have synced since the store was
+// opened, for commands that keep it open like mark serve.
+func (db *DB) Refresh() error {
+	return syncronizeFromHostsToDB(db, db.Hostname, db.ChangesStoreLoc)
+}
+
 func EnsureTables(db *DB, tables ...requirement) error {
 	for _, table := range tables {
 		_, err := db.Exec(table.definition)
@@ -153,10 +264,88 @@ func EnsureTables(db *DB, tables ...requirement) error {
 	return nil
 }
 
-func InsertBookmark(db *DB, bookmark Bookmark) (BookmarkId, error) {
-	tags := strings.Join(bookmark.Tags, ", ")
-	result, err := db.Exec("INSERT INTO Bookmarks (url, title, description, tags) VALUES (?, ?, ?, ?)",
-		bookmark.Url, bookmark.Title, bookmark.Description, tags)
+const bookmarkColumns = `b.id, b.url, b.title, b.description, b.tags, COALESCE(b.canonical_url, ''), b.created_at, b.updated_at,
+	COALESCE(b.original_url, ''), COALESCE(b.resolved_url, ''), COALESCE(b.canonical_link, ''), COALESCE(b.redirects, ''),
+	COALESCE(b.user_fields, ''), COALESCE(b.keywords, ''), COALESCE(b.notes, ''), COALESCE(b.unread, 0),
+	COALESCE(b.linked_url, ''), COALESCE(b.authors, ''), COALESCE(b.stars, 0), COALESCE(b.duration, 0)`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBookmark(row scanner) (Bookmark, error) {
+	var b Bookmark
+	var tags string
+	var createdAt, updatedAt sql.NullInt64
+	var redirects, userFields, keywords, authors string
+	var duration int64
+	err := row.Scan(&b.Id, &b.Url, &b.Title, &b.Description, &tags, &b.CanonicalUrl, &createdAt, &updatedAt,
+		&b.OriginalUrl, &b.ResolvedUrl, &b.CanonicalLink, &redirects, &userFields, &keywords,
+		&b.Notes, &b.Unread, &b.LinkedUrl, &authors, &b.Stars, &duration)
+	b.Tags = splitTags(tags)
+	b.Redirects = splitLines(redirects)
+	b.UserFields = splitTags(userFields)
+	b.Keywords = splitLines(keywords)
+	b.Authors = splitLines(authors)
+	b.Duration = time.Duration(duration) * time.Second
+	b.CreatedAt = fromUnix(createdAt)
+	b.UpdatedAt = fromUnix(updatedAt)
+	return b, err
+}
+
+func scanBookmarks(rows *sql.Rows) ([]Bookmark, error) {
+	defer rows.Close()
+
+	bookmarks := []Bookmark{}
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			return bookmarks, err
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+func fromUnix(seconds sql.NullInt64) time.Time {
+	if !seconds.Valid || seconds.Int64 == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds.Int64, 0)
+}
+
+func toUnix(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Unix()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// InsertBookmark saves a new bookmark, returning a *DuplicateError if a
+// bookmark with the same canonical url already exists. CreatedAt defaults to
+// now unless it is set, for example by an import.
+func InsertBookmark(db Querier, bookmark Bookmark) (BookmarkId, error) {
+	canonical, err := checkDuplicate(db, bookmark.Url, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if bookmark.CreatedAt.IsZero() {
+		bookmark.CreatedAt = time.Now()
+	}
+	if bookmark.UpdatedAt.IsZero() {
+		bookmark.UpdatedAt = bookmark.CreatedAt
+	}
+
+	result, err := db.Exec(`INSERT INTO Bookmarks (`+savedColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		savedValues(bookmark, canonical)...)
 	if err != nil {
 		return 0, err
 	}
@@ -164,42 +353,252 @@ func InsertBookmark(db *DB, bookmark Bookmark) (BookmarkId, error) {
 	return BookmarkId(id), err
 }
 
-func SearchBookmarks(db *DB, query string) ([]Bookmark, error) {
-	bookmarks := []Bookmark{}
-	rows, err := db.Query(`SELECT url, title, description, tags FROM Bookmarks_fts WHERE Bookmarks_fts MATCH ?;`, query)
+// savedColumns are the columns InsertBookmark and RestoreBookmark write, in
+// the order of savedValues.
+const savedColumns = `url, title, description, tags, canonical_url, created_at, updated_at,
+	original_url, resolved_url, canonical_link, redirects, user_fields, keywords, notes, unread,
+	linked_url, authors, stars, duration`
+
+func savedValues(bookmark Bookmark, canonical string) []any {
+	return []any{bookmark.Url, bookmark.Title, bookmark.Description, joinTags(bookmark.Tags), canonical,
+		toUnix(bookmark.CreatedAt), toUnix(bookmark.UpdatedAt),
+		bookmark.OriginalUrl, bookmark.ResolvedUrl, bookmark.CanonicalLink, strings.Join(bookmark.Redirects, "\n"),
+		joinTags(bookmark.UserFields), strings.Join(bookmark.Keywords, "\n"), bookmark.Notes, bookmark.Unread,
+		bookmark.LinkedUrl, strings.Join(bookmark.Authors, "\n"), bookmark.Stars, int64(bookmark.Duration / time.Second)}
+}
+
+// RestoreBookmark saves bookmark under its own Id exactly as given,
+// timestamps included, replacing the bookmark with that id if there is one.
+// It returns a *DuplicateError if a bookmark with another id has the same
+// canonical url.
+func RestoreBookmark(db Querier, bookmark Bookmark) error {
+	canonical, err := checkDuplicate(db, bookmark.Url, bookmark.Id)
 	if err != nil {
-		return bookmarks, err
+		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var b Bookmark
-		var tags string
-		err := rows.Scan(&b.Url, &b.Title, &b.Description, &tags)
-		if err != nil {
-			return bookmarks, err
-		}
-		b.Tags = strings.Split(tags, ", ")
-		bookmarks = append(bookmarks, b)
+	_, err = GetBookmark(db, bookmark.Id)
+	if errors.Is(err, ErrNotFound) {
+		_, err = db.Exec(`INSERT INTO Bookmarks (id, `+savedColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			append([]any{bookmark.Id}, savedValues(bookmark, canonical)...)...)
+		return err
 	}
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE Bookmarks SET (`+savedColumns+`) = (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) WHERE id = ?;`,
+		append(savedValues(bookmark, canonical), bookmark.Id)...)
+	return err
+}
+
+func SearchBookmarks(db Querier, query string) ([]Bookmark, error) {
+	rows, err := db.Query(`SELECT `+bookmarkColumns+` FROM Bookmarks_fts
+	JOIN Bookmarks b ON b.id = Bookmarks_fts.rowid
+	WHERE Bookmarks_fts MATCH ?;`, query)
+	if err != nil {
+		return []Bookmark{}, err
+	}
+
+	return scanBookmarks(rows)
+}
+
+// GetBookmark looks up a single bookmark by its id, returning ErrNotFound if
+// there is no such bookmark.
+func GetBookmark(db Querier, id BookmarkId) (Bookmark, error) {
+	b, err := scanBookmark(db.QueryRow(`SELECT `+bookmarkColumns+` FROM Bookmarks b WHERE b.id = ?;`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return b, ErrNotFound
+	}
+	return b, err
+}
+
+// FindBookmarkByURL looks up the bookmark whose canonical url matches the
+// canonical form of rawURL, returning ErrNotFound if there is none.
+func FindBookmarkByURL(db Querier, rawURL string) (Bookmark, error) {
+	canonical, err := CanonicalURL(rawURL, db.store().Config.Canonical)
+	if err != nil {
+		return Bookmark{}, err
+	}
+	return findCanonical(db, canonical, 0)
+}
+
+func findCanonical(db Querier, canonical string, except BookmarkId) (Bookmark, error) {
+	b, err := scanBookmark(db.QueryRow(`SELECT `+bookmarkColumns+` FROM Bookmarks b
+	WHERE b.canonical_url = ? AND b.id != ?
+	ORDER BY b.id LIMIT 1;`, canonical, except))
+	if errors.Is(err, sql.ErrNoRows) {
+		return b, ErrNotFound
+	}
+	return b, err
+}
 
-	return bookmarks, nil
+// checkDuplicate returns the canonical form of rawURL, or a *DuplicateError
+// if a bookmark other than except already has it.
+func checkDuplicate(db Querier, rawURL string, except BookmarkId) (string, error) {
+	canonical, err := CanonicalURL(rawURL, db.store().Config.Canonical)
+	if err != nil {
+		return "", errors.Join(fmt.Errorf("unable to parse url: %s", rawURL), err)
+	}
+
+	existing, err := findCanonical(db, canonical, except)
+	if err == nil {
+		return "", &DuplicateError{Existing: existing}
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	return canonical, nil
 }
 
-func UpdateBookmark(db *DB, original Bookmark, updated Bookmark) error {
-	_, err := db.Exec(`UPDATE Bookmarks SET 
+// UpdateBookmark saves the changes to a bookmark, returning a *DuplicateError
+// if the new url is already used by another bookmark. A zero CreatedAt keeps
+// the current creation time, and UpdatedAt is set to now.
+func UpdateBookmark(db Querier, original Bookmark, updated Bookmark) error {
+	canonical, err := checkDuplicate(db, updated.Url, original.Id)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`UPDATE Bookmarks SET 
 		url = ?,
 		title = ?,
 		description = ?,
-		tags = ?
+		tags = ?,
+		canonical_url = ?,
+		created_at = COALESCE(?, created_at),
+		updated_at = ?,
+		original_url = ?,
+		resolved_url = ?,
+		canonical_link = ?,
+		redirects = ?,
+		user_fields = ?,
+		keywords = ?,
+		notes = ?,
+		unread = ?,
+		linked_url = ?,
+		authors = ?,
+		stars = ?,
+		duration = ?
 	WHERE 
-		url = ?;`,
+		id = ?;`,
 		updated.Url,
 		updated.Title,
 		updated.Description,
-		strings.Join(updated.Tags, ", "),
-		original.Url,
+		joinTags(updated.Tags),
+		canonical,
+		toUnix(updated.CreatedAt),
+		time.Now().Unix(),
+		updated.OriginalUrl,
+		updated.ResolvedUrl,
+		updated.CanonicalLink,
+		strings.Join(updated.Redirects, "\n"),
+		joinTags(updated.UserFields),
+		strings.Join(updated.Keywords, "\n"),
+		updated.Notes,
+		updated.Unread,
+		updated.LinkedUrl,
+		strings.Join(updated.Authors, "\n"),
+		updated.Stars,
+		int64(updated.Duration/time.Second),
+		original.Id,
 	)
 
 	return err
 }
+
+// ListBookmarks returns every bookmark in the store, oldest first.
+func ListBookmarks(db Querier) ([]Bookmark, error) {
+	rows, err := db.Query(`SELECT ` + bookmarkColumns + ` FROM Bookmarks b ORDER BY b.id;`)
+	if err != nil {
+		return []Bookmark{}, err
+	}
+
+	return scanBookmarks(rows)
+}
+
+// EachBookmark calls fn with the bookmarks matching a full text query, or
+// every bookmark in the store when query is empty, in the order of their
+// ids. They are read one at a time, so large stores don't have to fit in
+// memory, and an error from fn stops the iteration.
+func EachBookmark(db Querier, query string, fn func(Bookmark) error) error {
+	var rows *sql.Rows
+	var err error
+	if query == "" {
+		rows, err = db.Query(`SELECT ` + bookmarkColumns + ` FROM Bookmarks b ORDER BY b.id;`)
+	} else {
+		rows, err = db.Query(`SELECT `+bookmarkColumns+` FROM Bookmarks_fts
+	JOIN Bookmarks b ON b.id = Bookmarks_fts.rowid
+	WHERE Bookmarks_fts MATCH ? ORDER BY b.id;`, query)
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DeleteBookmark removes a bookmark. The delete is recorded by cr-sqlite so
+// that it propagates to the other hosts on the next sync.
+func DeleteBookmark(db Querier, id BookmarkId) error {
+	_, err := db.Exec(`DELETE FROM Bookmarks WHERE id = ?;`, id)
+	if err != nil {
+		return err
+	}
+	if err := CompleteEnrichment(db, id); err != nil {
+		return err
+	}
+	// the files stay in the cache until mark cache prune
+	return forgetImages(db, id)
+}
+
+// CanonicalizeBookmarks recomputes the canonical url of every bookmark with
+// the current rules, for example after they were changed in config.toml.
+func CanonicalizeBookmarks(tx *Tx) error {
+	rows, err := tx.Query(`SELECT id, url, COALESCE(canonical_url, '') FROM Bookmarks;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type pending struct {
+		id        BookmarkId
+		canonical string
+	}
+	updates := []pending{}
+	for rows.Next() {
+		var id BookmarkId
+		var rawURL, current string
+		if err := rows.Scan(&id, &rawURL, &current); err != nil {
+			return err
+		}
+		canonical, err := CanonicalURL(rawURL, tx.db.Config.Canonical)
+		if err != nil {
+			canonical = rawURL
+		}
+		if canonical != current {
+			updates = append(updates, pending{id, canonical})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, update := range updates {
+		_, err := tx.Exec(`UPDATE Bookmarks SET canonical_url = ? WHERE id = ?;`, update.canonical, update.id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FindDuplicates groups bookmarks that share a canonical url, and when
+// byTitle is set also those with the same title. Only groups with more than
+// one bookmark are returned, each sorted by id.
+func FindDuplicates(db Querier, byTitle bool) ([][]Bookmark, error) {
+	bookmarks, err := ListBookmarks(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// union-find over the index of each bookmark, so that a bookmark
+	// matching one group by url and another by title joins them together
+	parent := make([]int, len(bookmarks))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(keys map[string]int, key string, i int) {
+		if key == "" {
+			return
+		}
+		if j, ok := keys[key]; ok {
+			parent[find(i)] = find(j)
+			return
+		}
+		keys[key] = i
+	}
+
+	urls := map[string]int{}
+	titles := map[string]int{}
+	for i, b := range bookmarks {
+		canonical := b.CanonicalUrl
+		if canonical == "" {
+			canonical = b.Url
+		}
+		union(urls, canonical, i)
+		if byTitle {
+			union(titles, strings.ToLower(strings.TrimSpace(b.Title)), i)
+		}
+	}
+
+	groups := map[int][]Bookmark{}
+	for i, b := range bookmarks {
+		root := find(i)
+		groups[root] = append(groups[root], b)
+	}
+
+	duplicates := [][]Bookmark{}
+	for _, group := range groups {
+		if len(group) > 1 {
+			duplicates = append(duplicates, group)
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i][0].Id < duplicates[j][0].Id
+	})
+
+	return duplicates, nil
+}
+
+// MergeBookmarks folds the duplicates into keep: tags are unioned, the
+// longest description and the earliest creation time win, and differing notes
+// are kept one after the other. The duplicates are
+// deleted, which cr-sqlite records so the merge syncs to the other hosts.
+func MergeBookmarks(db Querier, keep Bookmark, duplicates []Bookmark) (Bookmark, error) {
+	merged := keep
+	for _, dup := range duplicates {
+		if dup.Id == keep.Id {
+			continue
+		}
+
+		merged.Tags = MergeTags(merged.Tags, dup.Tags)
+		if len(dup.Description) > len(merged.Description) {
+			merged.Description = dup.Description
+		}
+		if merged.Title == "" {
+			merged.Title = dup.Title
+		}
+		if dup.Notes != "" && !strings.Contains(merged.Notes, dup.Notes) {
+			merged.Notes = strings.TrimSpace(merged.Notes + "\n\n" + dup.Notes)
+		}
+		if merged.LinkedUrl == "" {
+			merged.LinkedUrl = dup.LinkedUrl
+		}
+		if len(merged.Authors) == 0 {
+			merged.Authors = dup.Authors
+		}
+		if merged.Stars == 0 {
+			merged.Stars = dup.Stars
+		}
+		if merged.Duration == 0 {
+			merged.Duration = dup.Duration
+		}
+		if !dup.CreatedAt.IsZero() && (merged.CreatedAt.IsZero() || dup.CreatedAt.Before(merged.CreatedAt)) {
+			merged.CreatedAt = dup.CreatedAt
+		}
+
+		if err := DeleteBookmark(db, dup.Id); err != nil {
+			return keep, errors.Join(fmt.Errorf("unable to delete duplicate %d", dup.Id), err)
+		}
+	}
+
+	if err := UpdateBookmark(db, keep, merged); err != nil {
+		return keep, errors.Join(fmt.Errorf("unable to update bookmark %d", keep.Id), err)
+	}
+	return merged, nil
+}
@@ -0,0 +1,85 @@
+package store
+
+import "time"
+
+// EnrichmentJob is a bookmark that still needs its metadata fetched.
+type EnrichmentJob struct {
+	BookmarkId BookmarkId
+	// Primary is the url the bookmark should end up saved under, one of
+	// original, resolved or canonical.
+	Primary     string
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+}
+
+// MaxEnrichmentAttempts is how many times a job is tried before it is
+// considered failed and only retried when asked for.
+const MaxEnrichmentAttempts = 6
+
+// Failed reports whether the job has used up all of its attempts.
+func (j EnrichmentJob) Failed() bool {
+	return j.Attempts >= MaxEnrichmentAttempts
+}
+
+// EnqueueEnrichment records that a bookmark's metadata should be fetched.
+func EnqueueEnrichment(db Querier, id BookmarkId, primary string) error {
+	_, err := db.Exec(`INSERT INTO Enrichment_Jobs (bookmark_id, primary_url, next_attempt) VALUES (?, ?, ?)
+	ON CONFLICT (bookmark_id) DO UPDATE SET primary_url = excluded.primary_url, attempts = 0, next_attempt = excluded.next_attempt;`,
+		id, primary, time.Now().Unix())
+	return err
+}
+
+// PendingEnrichments returns the jobs that are due at now. Failed jobs and
+// the schedule of backed off jobs are ignored when includeFailed is set.
+func PendingEnrichments(db Querier, now time.Time, includeFailed bool) ([]EnrichmentJob, error) {
+	query := `SELECT bookmark_id, primary_url, attempts, next_attempt, COALESCE(last_error, '') FROM Enrichment_Jobs
+	WHERE next_attempt <= ? AND attempts < ? ORDER BY next_attempt;`
+	args := []any{now.Unix(), MaxEnrichmentAttempts}
+	if includeFailed {
+		query = `SELECT bookmark_id, primary_url, attempts, next_attempt, COALESCE(last_error, '') FROM Enrichment_Jobs
+		ORDER BY next_attempt;`
+		args = nil
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []EnrichmentJob{}
+	for rows.Next() {
+		var job EnrichmentJob
+		var next int64
+		if err := rows.Scan(&job.BookmarkId, &job.Primary, &job.Attempts, &next, &job.LastError); err != nil {
+			return jobs, err
+		}
+		job.NextAttempt = time.Unix(next, 0)
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CompleteEnrichment removes the job once the metadata has been saved.
+func CompleteEnrichment(db Querier, id BookmarkId) error {
+	_, err := db.Exec(`DELETE FROM Enrichment_Jobs WHERE bookmark_id = ?;`, id)
+	return err
+}
+
+// FailEnrichment records a failed attempt and schedules the next one with
+// exponential backoff, starting at a minute and capped at a day.
+func FailEnrichment(db Querier, job EnrichmentJob, cause error) (EnrichmentJob, error) {
+	job.Attempts++
+	job.LastError = cause.Error()
+
+	backoff := time.Minute << (job.Attempts - 1)
+	if backoff > 24*time.Hour || backoff <= 0 {
+		backoff = 24 * time.Hour
+	}
+	job.NextAttempt = time.Now().Add(backoff)
+
+	_, err := db.Exec(`UPDATE Enrichment_Jobs SET attempts = ?, next_attempt = ?, last_error = ? WHERE bookmark_id = ?;`,
+		job.Attempts, job.NextAttempt.Unix(), job.LastError, job.BookmarkId)
+	return job, err
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Feed is a subscription mark feeds poll adds bookmarks from.
+type Feed struct {
+	Url   string
+	Title string
+	// Tags are added to the bookmarks of the feed's items, along with
+	// Title.
+	Tags []string
+	// Filter is a regular expression items have to match to be
+	// bookmarked, empty for every item.
+	Filter   string
+	AddedAt  time.Time
+	PolledAt time.Time
+	// ETag and LastModified are sent back on the next poll, so an unchanged
+	// feed isn't downloaded again.
+	ETag         string
+	LastModified string
+	LastError    string
+}
+
+const feedColumns = `url, COALESCE(title, ''), COALESCE(tags, ''), COALESCE(filter, ''), added_at, polled_at,
+	COALESCE(etag, ''), COALESCE(last_modified, ''), COALESCE(last_error, '')`
+
+func scanFeed(row scanner) (Feed, error) {
+	var f Feed
+	var tags string
+	var addedAt, polledAt sql.NullInt64
+	err := row.Scan(&f.Url, &f.Title, &tags, &f.Filter, &addedAt, &polledAt, &f.ETag, &f.LastModified, &f.LastError)
+	f.Tags = splitTags(tags)
+	f.AddedAt = fromUnix(addedAt)
+	f.PolledAt = fromUnix(polledAt)
+	return f, err
+}
+
+// ErrSubscribed is returned by AddFeed for feeds that are subscribed to
+// already.
+var ErrSubscribed = errors.New("already subscribed to the feed")
+
+// AddFeed subscribes to a feed.
+func AddFeed(db Querier, feed Feed) error {
+	if _, err := GetFeed(db, feed.Url); err == nil {
+		return ErrSubscribed
+	}
+	_, err := db.Exec(`INSERT INTO Feeds (url, title, tags, filter, added_at) VALUES (?, ?, ?, ?, ?);`,
+		feed.Url, feed.Title, joinTags(feed.Tags), feed.Filter, time.Now().Unix())
+	return err
+}
+
+// GetFeed looks up a subscription, returning ErrNotFound if there is none.
+func GetFeed(db Querier, url string) (Feed, error) {
+	f, err := scanFeed(db.QueryRow(`SELECT `+feedColumns+` FROM Feeds WHERE url = ?;`, url))
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, ErrNotFound
+	}
+	return f, err
+}
+
+// ListFeeds returns every subscription, in the order they were added.
+func ListFeeds(db Querier) ([]Feed, error) {
+	rows, err := db.Query(`SELECT ` + feedColumns + ` FROM Feeds ORDER BY added_at, url;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := []Feed{}
+	for rows.Next() {
+		f, err := scanFeed(rows)
+		if err != nil {
+			return feeds, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// RemoveFeed unsubscribes from a feed and forgets its items. The bookmarks
+// added from it are kept.
+func RemoveFeed(db Querier, url string) error {
+	result, err := db.Exec(`DELETE FROM Feeds WHERE url = ?;`, url)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	_, err = db.Exec(`DELETE FROM Feed_Items WHERE feed_url = ?;`, url)
+	return err
+}
+
+// RecordPoll saves the outcome of polling a feed: its title, caching headers
+// and the error if it failed.
+func RecordPoll(db Querier, feed Feed) error {
+	_, err := db.Exec(`UPDATE Feeds SET title = ?, polled_at = ?, etag = ?, last_modified = ?, last_error = ? WHERE url = ?;`,
+		feed.Title, toUnix(feed.PolledAt), feed.ETag, feed.LastModified, feed.LastError, feed.Url)
+	return err
+}
+
+// SeenFeedItem reports whether an item of the feed was seen before.
+func SeenFeedItem(db Querier, feedURL string, itemID string) (bool, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM Feed_Items WHERE feed_url = ? AND item_id = ?;`, feedURL, itemID).Scan(&n)
+	return n > 0, err
+}
+
+// MarkFeedItemSeen remembers an item so it isn't bookmarked again.
+func MarkFeedItemSeen(db Querier, feedURL string, itemID string) error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO Feed_Items (feed_url, item_id, seen_at) VALUES (?, ?, ?);`,
+		feedURL, itemID, time.Now().Unix())
+	return err
+}
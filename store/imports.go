@@ -0,0 +1,39 @@
+package store
+
+import (
+	"time"
+)
+
+// ImportedItems returns the ids of the items imported from source on this
+// host, like the guids a browser keeps for its bookmarks.
+func ImportedItems(db Querier, source string) (map[string]bool, error) {
+	rows, err := db.Query(`SELECT item_id FROM Imported_Items WHERE source = ?;`, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// RecordImportedItems remembers the items imported from source, so the next
+// incremental import can leave them out however old they are.
+func RecordImportedItems(db Querier, source string, ids []string) error {
+	now := time.Now().Unix()
+	for _, id := range ids {
+		_, err := db.Exec(`INSERT INTO Imported_Items (source, item_id, imported_at) VALUES (?, ?, ?)
+		ON CONFLICT (source, item_id) DO UPDATE SET imported_at = excluded.imported_at;`, source, id, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+type migration struct {
+	name       string
+	statements []string
+	// apply runs after the statements for changes that need more than sql.
+	apply func(tx *Tx) error
+}
+
+// Migrations change the schema of databases made by older versions of mark.
+// They run in order once Bookmarks is a crr, and PRAGMA user_version records
+// how many of them have been applied. Columns added to a crr have to be
+// wrapped in crsql_begin_alter and crsql_commit_alter.
+var Migrations = []migration{
+	{
+		name: "canonical_url",
+		statements: []string{
+			`SELECT crsql_begin_alter('Bookmarks');`,
+			`ALTER TABLE Bookmarks ADD COLUMN canonical_url TEXT;`,
+			`SELECT crsql_commit_alter('Bookmarks');`,
+			// cr-sqlite doesn't allow unique indices besides the primary key
+			// on crrs, so uniqueness is enforced by InsertBookmark instead.
+			`CREATE INDEX IF NOT EXISTS Bookmarks_canonical_url ON Bookmarks (canonical_url);`,
+		},
+		apply: CanonicalizeBookmarks,
+	},
+	{
+		name: "timestamps",
+		statements: []string{
+			`SELECT crsql_begin_alter('Bookmarks');`,
+			`ALTER TABLE Bookmarks ADD COLUMN created_at INTEGER;`,
+			`ALTER TABLE Bookmarks ADD COLUMN updated_at INTEGER;`,
+			`SELECT crsql_commit_alter('Bookmarks');`,
+		},
+	},
+	{
+		name: "resolved_urls",
+		statements: []string{
+			`SELECT crsql_begin_alter('Bookmarks');`,
+			`ALTER TABLE Bookmarks ADD COLUMN original_url TEXT;`,
+			`ALTER TABLE Bookmarks ADD COLUMN resolved_url TEXT;`,
+			`ALTER TABLE Bookmarks ADD COLUMN canonical_link TEXT;`,
+			`ALTER TABLE Bookmarks ADD COLUMN redirects TEXT;`,
+			`SELECT crsql_commit_alter('Bookmarks');`,
+		},
+	},
+	{
+		name: "user_fields",
+		statements: []string{
+			`SELECT crsql_begin_alter('Bookmarks');`,
+			`ALTER TABLE Bookmarks ADD COLUMN user_fields TEXT;`,
+			`SELECT crsql_commit_alter('Bookmarks');`,
+		},
+	},
+	{
+		name: "keywords",
+		statements: []string{
+			`SELECT crsql_begin_alter('Bookmarks');`,
+			`ALTER TABLE Bookmarks ADD COLUMN keywords TEXT;`,
+			`SELECT crsql_commit_alter('Bookmarks');`,
+		},
+	},
+	{
+		name: "notes",
+		statements: []string{
+			`SELECT crsql_begin_alter('Bookmarks');`,
+			`ALTER TABLE Bookmarks ADD COLUMN notes TEXT;`,
+			`ALTER TABLE Bookmarks ADD COLUMN unread INTEGER;`,
+			`SELECT crsql_commit_alter('Bookmarks');`,
+		},
+	},
+	{
+		name: "enriched_fields",
+		statements: []string{
+			`SELECT crsql_begin_alter('Bookmarks');`,
+			`ALTER TABLE Bookmarks ADD COLUMN linked_url TEXT;`,
+			`ALTER TABLE Bookmarks ADD COLUMN authors TEXT;`,
+			`ALTER TABLE Bookmarks ADD COLUMN stars INTEGER;`,
+			`ALTER TABLE Bookmarks ADD COLUMN duration INTEGER;`,
+			`SELECT crsql_commit_alter('Bookmarks');`,
+		},
+	},
+}
+
+func Migrate(db *DB) error {
+	var version int
+	err := db.QueryRow(`PRAGMA user_version;`).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(Migrations); i++ {
+		m := Migrations[i]
+		err := Transaction(db, func(tx *Tx) error {
+			for _, statement := range m.statements {
+				if _, err := tx.Exec(statement); err != nil {
+					return err
+				}
+			}
+			if m.apply != nil {
+				if err := m.apply(tx); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d;`, i+1))
+			return err
+		})
+		if err != nil {
+			return errors.Join(fmt.Errorf("migration %d %s failed", i+1, m.name), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// Package storetest opens stores for tests. cr-sqlite isn't needed: its
+// functions do nothing and crsql_changes is an empty table, so everything but
+// syncing works like in a real store.
+package storetest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+	"github.com/mattn/go-sqlite3"
+)
+
+const driver = "sqlite3-storetest"
+
+var registerDriver sync.Once
+
+// crsqlFunctions stand in for the ones of the cr-sqlite extension.
+var crsqlFunctions = map[string]any{
+	"crsql_as_crr":       func(table string) int64 { return 0 },
+	"crsql_begin_alter":  func(table string) int64 { return 0 },
+	"crsql_commit_alter": func(table string) int64 { return 0 },
+	"crsql_finalize":     func() int64 { return 0 },
+	"crsql_site_id":      func() []byte { return []byte("storetest") },
+}
+
+// Open makes an empty store in a temporary directory, which is removed
+// along with the store when the test ends.
+func Open(t testing.TB) *store.DB {
+	t.Helper()
+	registerDriver.Do(func() {
+		sql.Register(driver, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				for name, fn := range crsqlFunctions {
+					if err := conn.RegisterFunc(name, fn, true); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	})
+
+	dir := t.TempDir()
+	sqlDB, err := sql.Open(driver, filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db := &store.DB{
+		DB:              sqlDB,
+		StoreLoc:        dir,
+		ChangesStoreLoc: filepath.Join(dir, "changes"),
+		Hostname:        "storetest",
+		Config:          store.DefaultConfig(),
+	}
+
+	// without the fts5 build tag the search index is a plain table, which
+	// keeps the triggers working but can't be searched
+	if _, err := db.Exec(`CREATE VIRTUAL TABLE temp.fts5_probe USING fts5(x);`); err != nil {
+		if _, err := db.Exec(`CREATE TABLE Bookmarks_fts (url, title, description, tags);`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.EnsureDirExists(db.ChangesStoreLoc); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE crsql_changes ("table", pk, cid, val, col_version, db_version, site_id, cl, seq);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnsureTables(db, store.Tables...); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,22 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/lukasmwerner/mark/store"
+)
+
+func TestOpen(t *testing.T) {
+	db := Open(t)
+	id, err := store.InsertBookmark(db, store.Bookmark{Url: "https://example.com", Title: "Example", Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.GetBookmark(db, id)
+	if err != nil || b.Title != "Example" {
+		t.Fatalf("got %+v, %v", b, err)
+	}
+	if _, err := store.InsertBookmark(db, store.Bookmark{Url: "https://example.com/"}); err == nil {
+		t.Error("inserted a duplicate")
+	}
+}
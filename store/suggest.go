@@ -0,0 +1,151 @@
+package store
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TagSuggestion is a tag that probably belongs on a bookmark.
+type TagSuggestion struct {
+	Tag string
+	// Reasons say where the suggestion came from: "domain", "similar" or
+	// "keyword".
+	Reasons []string
+	Score   float64
+}
+
+// similarLimit is how many of the most similar bookmarks lend their tags.
+const similarLimit = 20
+
+// SuggestTags suggests tags for a bookmark from the rest of the collection:
+// the tags used on the same domain, the tags of bookmarks with similar titles
+// and descriptions (ranked with bm25 over the search index), and the page's
+// keywords. Tags the bookmark already has are left out. The best limit
+// suggestions are returned, or all of them when limit is 0.
+func SuggestTags(db Querier, b Bookmark, limit int) ([]TagSuggestion, error) {
+	suggestions := map[string]*TagSuggestion{}
+	suggest := func(tag string, reason string, score float64) {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return
+		}
+		s, ok := suggestions[tag]
+		if !ok {
+			s = &TagSuggestion{Tag: tag}
+			suggestions[tag] = s
+		}
+		if len(s.Reasons) == 0 || s.Reasons[len(s.Reasons)-1] != reason {
+			s.Reasons = append(s.Reasons, reason)
+		}
+		s.Score += score
+	}
+
+	bookmarks, err := ListBookmarks(db)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]string{}
+	host := suggestionHost(b.Url)
+	for _, other := range bookmarks {
+		for _, tag := range other.Tags {
+			known[strings.ToLower(tag)] = tag
+		}
+		if other.Id == b.Id || host == "" || suggestionHost(other.Url) != host {
+			continue
+		}
+		for _, tag := range other.Tags {
+			suggest(tag, "domain", 2)
+		}
+	}
+
+	if query := similarityQuery(b.Title + " " + b.Description); query != "" {
+		rows, err := db.Query(`SELECT b.tags, bm25(Bookmarks_fts, 0.0, 2.0, 1.0, 0.0) AS rank FROM Bookmarks_fts
+		JOIN Bookmarks b ON b.id = Bookmarks_fts.rowid
+		WHERE Bookmarks_fts MATCH ? AND b.id != ?
+		ORDER BY rank LIMIT ?;`, query, b.Id, similarLimit)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var tags string
+			var rank float64
+			if err := rows.Scan(&tags, &rank); err != nil {
+				return nil, err
+			}
+			// bm25 is negative, the more relevant the lower
+			for _, tag := range splitTags(tags) {
+				suggest(tag, "similar", -rank)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, keyword := range b.Keywords {
+		// prefer the spelling of a tag that is already in use
+		tag, ok := known[strings.ToLower(strings.TrimSpace(keyword))]
+		score := 2.0
+		if !ok {
+			tag = strings.ToLower(keyword)
+			score = 1
+		}
+		suggest(tag, "keyword", score)
+	}
+
+	for _, tag := range b.Tags {
+		delete(suggestions, tag)
+	}
+
+	list := []TagSuggestion{}
+	for _, s := range suggestions {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].Tag < list[j].Tag
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func suggestionHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true,
+	"from": true, "your": true, "you": true, "are": true, "was": true, "but": true,
+	"not": true, "how": true, "what": true, "why": true, "when": true, "into": true,
+	"about": true, "its": true, "our": true, "can": true, "all": true, "has": true,
+	"have": true, "will": true, "more": true, "new": true, "use": true, "using": true,
+}
+
+// similarityQuery turns text into an fts5 query matching any of its words.
+func similarityQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range words {
+		if len([]rune(word)) < 3 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		// quoted so words like "or" and "near" aren't read as operators
+		terms = append(terms, `"`+word+`"`)
+	}
+	return strings.Join(terms, " OR ")
+}
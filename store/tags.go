@@ -0,0 +1,48 @@
+package store
+
+import "strings"
+
+// NormalizeTags trims whitespace from every tag and drops empty and repeated
+// tags while keeping the original order.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// MergeTags returns the union of both tag lists, keeping the order of tags
+// followed by any new tags from other.
+func MergeTags(tags []string, other []string) []string {
+	return NormalizeTags(append(append([]string{}, tags...), other...))
+}
+
+// RemoveTags returns tags without any of the tags in remove.
+func RemoveTags(tags []string, remove []string) []string {
+	drop := map[string]bool{}
+	for _, tag := range remove {
+		drop[strings.TrimSpace(tag)] = true
+	}
+	kept := []string{}
+	for _, tag := range NormalizeTags(tags) {
+		if !drop[tag] {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+func joinTags(tags []string) string {
+	return strings.Join(NormalizeTags(tags), ", ")
+}
+
+func splitTags(tags string) []string {
+	return NormalizeTags(strings.Split(tags, ","))
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Querier is implemented by both *DB and *Tx so that the store functions can
+// be used on their own or grouped together inside a transaction.
+type Querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+
+	store() *DB
+}
+
+func (db *DB) store() *DB { return db }
+
+// Tx is a transaction on the store. Every change made through a Tx ends up
+// in the same cr-sqlite db_version, so it syncs as a single change batch.
+type Tx struct {
+	*sql.Tx
+
+	db *DB
+}
+
+func (tx *Tx) store() *DB { return tx.db }
+
+// Transaction runs fn inside a transaction, committing if fn succeeds and
+// rolling back otherwise.
+func Transaction(db *DB, fn func(tx *Tx) error) error {
+	sqlTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(&Tx{Tx: sqlTx, db: db})
+	if err != nil {
+		return errors.Join(err, sqlTx.Rollback())
+	}
+
+	return sqlTx.Commit()
+}
@@ -1,12 +1,95 @@
 package store
 
+import (
+	"strings"
+	"time"
+)
+
+// Bookmark is a saved page. Its json names are the ones mark prints fields
+// under, so scripts don't depend on the Go names.
 type Bookmark struct {
-	Url         string
-	Tags        []string
-	Title       string
-	Description string
+	Id          BookmarkId `json:"id"`
+	Url         string     `json:"url"`
+	Tags        []string   `json:"tags"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	// CanonicalUrl is the normalized form of Url used to find duplicates, it
+	// is computed by the store whenever the bookmark is saved.
+	CanonicalUrl string `json:"canonical_url"`
+	// CreatedAt and UpdatedAt are zero for bookmarks saved before mark kept
+	// track of them.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// OriginalUrl is the url as it was given when the bookmark was added,
+	// ResolvedUrl is where it ended up after following Redirects, and
+	// CanonicalLink is the page's <link rel="canonical">. Url is one of them.
+	OriginalUrl   string   `json:"original_url"`
+	ResolvedUrl   string   `json:"resolved_url"`
+	CanonicalLink string   `json:"canonical_link"`
+	Redirects     []string `json:"redirects"`
+
+	// UserFields are the fields ("title", "description", "url") that were set
+	// by hand, which enrichment never overwrites.
+	UserFields []string `json:"user_fields"`
+	// Keywords are the ones the page declared about itself, kept so rules
+	// can match them again later.
+	Keywords []string `json:"keywords"`
+	// Notes are the reader's own, kept apart from the page's Description.
+	// Unread marks bookmarks saved to read later.
+	Notes  string `json:"notes"`
+	Unread bool   `json:"unread"`
+
+	// LinkedUrl is the article a discussion on Hacker News or Reddit is
+	// about. Authors, Stars and Duration are what arXiv, GitHub and video
+	// sites tell about a page. They are filled in by enrichers.
+	LinkedUrl string        `json:"linked_url"`
+	Authors   []string      `json:"authors"`
+	Stars     int           `json:"stars"`
+	Duration  time.Duration `json:"duration"`
 }
 
 func (b Bookmark) FilterValue() string { return b.Url }
 
 type BookmarkId int64
+
+// TrackUserEdits returns after with every field that differs from before
+// added to its UserFields.
+func TrackUserEdits(before Bookmark, after Bookmark) Bookmark {
+	edited := []string{}
+	if before.Title != after.Title {
+		edited = append(edited, "title")
+	}
+	if before.Description != after.Description {
+		edited = append(edited, "description")
+	}
+	if before.Url != after.Url {
+		edited = append(edited, "url")
+	}
+	after.UserFields = MergeTags(after.UserFields, edited)
+	return after
+}
+
+// HasTags reports whether the bookmark has every one of tags.
+func (b Bookmark) HasTags(tags ...string) bool {
+	have := map[string]bool{}
+	for _, tag := range b.Tags {
+		have[strings.TrimSpace(tag)] = true
+	}
+	for _, tag := range tags {
+		if !have[strings.TrimSpace(tag)] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsUserField reports whether field was set by hand.
+func (b Bookmark) IsUserField(field string) bool {
+	for _, f := range b.UserFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
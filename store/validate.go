@@ -0,0 +1,124 @@
+package store
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Schemes are the kinds of urls that can be bookmarked. Only http and https
+// pages are fetched for their metadata.
+var Schemes = []string{"http", "https", "file", "mailto", "ssh", "obsidian"}
+
+var schemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+var hostLabelPattern = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_-]*[a-z0-9_])?$`)
+
+// NormalizeURL checks that raw is a url that can be bookmarked and returns it
+// cleaned up. Urls without a scheme, like example.com/page, are assumed to be
+// https.
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	input := raw
+	if raw == "" {
+		return "", fmt.Errorf("the url is empty")
+	}
+	if strings.ContainsAny(raw, " \t\r\n") {
+		return "", fmt.Errorf("%q is not a url, it contains spaces", raw)
+	}
+
+	// host:port looks like a scheme followed by an opaque part
+	scheme := schemePattern.FindString(raw)
+	if scheme == "" || isPort(strings.TrimPrefix(raw, scheme)) {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a url: %w", input, err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	switch u.Scheme {
+	case "http", "https":
+		if err := validHost(u.Hostname()); err != nil {
+			return "", fmt.Errorf("%q is not a url: %w", input, err)
+		}
+		host, err := asciiHost(u.Hostname())
+		if err != nil {
+			return "", fmt.Errorf("%q is not a url: %w", input, err)
+		}
+		if port := u.Port(); port != "" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		u.Host = host
+	case "ssh":
+		if u.Hostname() == "" {
+			return "", fmt.Errorf("%q is missing a host", input)
+		}
+	case "file":
+		if u.Path == "" {
+			return "", fmt.Errorf("%q is missing a path", input)
+		}
+	case "mailto":
+		if !strings.Contains(u.Opaque, "@") {
+			return "", fmt.Errorf("%q is missing an email address", input)
+		}
+	case "obsidian":
+		if u.Host == "" && u.RawQuery == "" {
+			return "", fmt.Errorf("%q doesn't point at anything in obsidian", input)
+		}
+	default:
+		return "", fmt.Errorf("%q has an unsupported scheme %s, expected one of %s", input, u.Scheme, strings.Join(Schemes, ", "))
+	}
+
+	return u.String(), nil
+}
+
+func isPort(rest string) bool {
+	port, _, _ := strings.Cut(rest, "/")
+	if port == "" {
+		return false
+	}
+	for _, r := range port {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// asciiHost returns internationalized domains in their ascii (punycode)
+// form.
+func asciiHost(host string) (string, error) {
+	for _, r := range host {
+		if r > 127 {
+			return idna.Lookup.ToASCII(host)
+		}
+	}
+	return strings.ToLower(host), nil
+}
+
+func validHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("it has no host")
+	}
+	if net.ParseIP(host) != nil || host == "localhost" {
+		return nil
+	}
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(host), "."), ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("%s is not a domain, expected something like example.com", host)
+	}
+	for _, label := range labels {
+		if !hostLabelPattern.MatchString(label) && !strings.ContainsFunc(label, func(r rune) bool { return r > 127 }) {
+			return fmt.Errorf("%s is not a valid domain", host)
+		}
+	}
+	return nil
+}